@@ -0,0 +1,868 @@
+package go2test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	ghk "github.com/cucumber/gherkin-go"
+)
+
+// ----------------------------------------------------------------------------------
+// Cucumber JSON document
+// The format is understood by the standard Cucumber report viewers
+// ----------------------------------------------------------------------------------
+type cucumberFeature struct {
+	URI          string                `json:"uri"`
+	ID           string                `json:"id"`
+	Keyword      string                `json:"keyword"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Line         int                   `json:"line"`
+	Tags         []*cucumberTag        `json:"tags,omitempty"`
+	Elements     []*cucumberElement    `json:"elements"`
+}
+
+type cucumberTag struct {
+	Name         string                `json:"name"`
+}
+
+type cucumberElement struct {
+	ID           string                `json:"id"`
+	Keyword      string                `json:"keyword"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Line         int                   `json:"line"`
+	Type         string                `json:"type"`
+	Tags         []*cucumberTag        `json:"tags,omitempty"`
+	Steps        []*cucumberStep       `json:"steps"`
+	After        []*cucumberStep       `json:"after,omitempty"`
+}
+
+type cucumberStep struct {
+	Keyword      string                `json:"keyword"`
+	Name         string                `json:"name"`
+	Line         int                   `json:"line"`
+	Match        *cucumberMatch        `json:"match,omitempty"`
+	Result       *cucumberResult       `json:"result"`
+	Rows         []*cucumberRow        `json:"rows,omitempty"`
+	DocString    *cucumberDocString    `json:"doc_string,omitempty"`
+	Embeddings   []*cucumberEmbedding  `json:"embeddings,omitempty"`
+}
+
+type cucumberDocString struct {
+	ContentType  string                `json:"content_type,omitempty"`
+	Value        string                `json:"value"`
+	Line         int                   `json:"line"`
+}
+
+type cucumberMatch struct {
+	Location     string                `json:"location"`
+}
+
+type cucumberResult struct {
+	Status       string                `json:"status"`
+	Duration     int64                 `json:"duration,omitempty"`
+	ErrorMessage string                `json:"error_message,omitempty"`
+}
+
+type cucumberRow struct {
+	Cells        []string              `json:"cells"`
+}
+
+type cucumberEmbedding struct {
+	MimeType     string                `json:"mime_type"`
+	Data         []byte                `json:"data"`
+}
+
+// Cucumber style id, lower case and joined by "-"
+func cucumberID(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
+
+// Cucumber style tags
+func cucumberTags(tags []string) []*cucumberTag {
+	ret := make([]*cucumberTag, 0, len(tags))
+	for _, tag := range tags {
+		ret = append(ret, &cucumberTag{Name: tag})
+	}
+	return ret
+}
+
+// Cucumber status of G2T_STATUS_*, a step which never ran is skipped
+func cucumberStatus(status int) string {
+	if status == G2T_STATUS_WAIT {
+		return statusName(G2T_STATUS_SKIP)
+	}
+	return statusName(status)
+}
+
+// Create Cucumber step from *Step
+func newCucumberStep(step *Step) *cucumberStep {
+	cs := new(cucumberStep)
+	cs.Keyword = step.Keyword
+	cs.Name = step.Text
+	cs.Line = step.Line
+	if location := actionLocation(step.Action); location != "" {
+		cs.Match = &cucumberMatch{Location: location}
+	}
+	cs.Result = new(cucumberResult)
+	cs.Result.Status = cucumberStatus(step.Status)
+	cs.Result.Duration = step.Duration.Nanoseconds()
+	if step.Exception != nil {
+		cs.Result.ErrorMessage = step.Exception.Message + "\n" + step.Exception.Stack
+	}
+	for _, row := range step.Rows {
+		cs.Rows = append(cs.Rows, &cucumberRow{Cells: row})
+	}
+	if step.DocString != nil {
+		cs.DocString = &cucumberDocString{ContentType: step.DocString.ContentType, Value: step.DocString.Content,
+			Line: step.Line + 1}
+	}
+	for _, attachment := range step.Attachments {
+		cs.Embeddings = append(cs.Embeddings, &cucumberEmbedding{MimeType: attachment.MimeType, Data: attachment.Data})
+	}
+	return cs
+}
+
+// Write the result as Cucumber JSON
+// @params:
+//    w: Where the JSON goes
+// @returns:
+//    (error): Errors of encoding or writing
+func (v *Result) WriteCucumberJSON(w io.Writer) error {
+	doc := make([]*cucumberFeature, 0, len(v.Features))
+	for _, feature := range v.Features {
+		cf := new(cucumberFeature)
+		cf.URI = feature.Path
+		cf.ID = cucumberID(feature.Name)
+		cf.Keyword = feature.Keyword
+		cf.Name = feature.Name
+		cf.Description = feature.Description
+		cf.Line = feature.Line
+		cf.Tags = cucumberTags(feature.Tags)
+		cf.Elements = make([]*cucumberElement, 0, len(feature.Scenarios))
+		for _, scenario := range feature.Scenarios {
+			ce := new(cucumberElement)
+			ce.ID = cf.ID + ";" + cucumberID(scenario.Name)
+			ce.Keyword = scenario.Keyword
+			ce.Name = scenario.Name
+			ce.Description = scenario.Description
+			ce.Line = scenario.Line
+			ce.Type = "scenario"
+			ce.Tags = cucumberTags(scenario.Tags)
+			ce.Steps = make([]*cucumberStep, 0, len(scenario.Steps))
+			for _, step := range scenario.Steps {
+				ce.Steps = append(ce.Steps, newCucumberStep(step))
+			}
+			for _, step := range scenario.AfterSteps {
+				ce.After = append(ce.After, newCucumberStep(step))
+			}
+			cf.Elements = append(cf.Elements, ce)
+		}
+		doc = append(doc, cf)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+
+// ----------------------------------------------------------------------------------
+// Cucumber Messages (NDJSON)
+// One envelope per line, the envelopes follow the schema of messagesProtocolVersion
+// Hooks registered in Go are not written
+// ----------------------------------------------------------------------------------
+const messagesProtocolVersion = "19.1.2"
+
+type messageTimestamp struct {
+	Seconds      int64                 `json:"seconds"`
+	Nanos        int64                 `json:"nanos"`
+}
+
+type messageDuration messageTimestamp
+
+func newMessageTimestamp(t time.Time) *messageTimestamp {
+	return &messageTimestamp{Seconds: t.Unix(), Nanos: int64(t.Nanosecond())}
+}
+
+func newMessageDuration(d time.Duration) *messageDuration {
+	return &messageDuration{Seconds: int64(d / time.Second), Nanos: int64(d % time.Second)}
+}
+
+type messageProduct struct {
+	Name         string                `json:"name"`
+	Version      string                `json:"version,omitempty"`
+}
+
+type messageMeta struct {
+	ProtocolVersion  string            `json:"protocolVersion"`
+	Implementation   *messageProduct   `json:"implementation"`
+	Runtime          *messageProduct   `json:"runtime"`
+	Os               *messageProduct   `json:"os"`
+	Cpu              *messageProduct   `json:"cpu"`
+}
+
+type messageSource struct {
+	URI          string                `json:"uri"`
+	Data         string                `json:"data"`
+	MediaType    string                `json:"mediaType"`
+}
+
+type messageLocation struct {
+	Line         int                   `json:"line"`
+	Column       int                   `json:"column,omitempty"`
+}
+
+type messageTag struct {
+	Location     *messageLocation      `json:"location"`
+	Name         string                `json:"name"`
+	ID           string                `json:"id"`
+}
+
+type messageComment struct {
+	Location     *messageLocation      `json:"location"`
+	Text         string                `json:"text"`
+}
+
+type messageGherkinTableCell struct {
+	Location     *messageLocation      `json:"location"`
+	Value        string                `json:"value"`
+}
+
+type messageGherkinTableRow struct {
+	Location     *messageLocation             `json:"location"`
+	Cells        []*messageGherkinTableCell   `json:"cells"`
+	ID           string                       `json:"id"`
+}
+
+type messageGherkinDataTable struct {
+	Location     *messageLocation             `json:"location"`
+	Rows         []*messageGherkinTableRow    `json:"rows"`
+}
+
+type messageGherkinDocString struct {
+	Location     *messageLocation      `json:"location"`
+	MediaType    string                `json:"mediaType,omitempty"`
+	Content      string                `json:"content"`
+	Delimiter    string                `json:"delimiter"`
+}
+
+type messageGherkinStep struct {
+	Location     *messageLocation          `json:"location"`
+	Keyword      string                    `json:"keyword"`
+	Text         string                    `json:"text"`
+	DocString    *messageGherkinDocString  `json:"docString,omitempty"`
+	DataTable    *messageGherkinDataTable  `json:"dataTable,omitempty"`
+	ID           string                    `json:"id"`
+}
+
+type messageExamples struct {
+	Location     *messageLocation             `json:"location"`
+	Tags         []*messageTag                `json:"tags"`
+	Keyword      string                       `json:"keyword"`
+	Name         string                       `json:"name"`
+	Description  string                       `json:"description"`
+	TableHeader  *messageGherkinTableRow      `json:"tableHeader,omitempty"`
+	TableBody    []*messageGherkinTableRow    `json:"tableBody"`
+	ID           string                       `json:"id"`
+}
+
+type messageScenario struct {
+	Location     *messageLocation      `json:"location"`
+	Tags         []*messageTag         `json:"tags"`
+	Keyword      string                `json:"keyword"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Steps        []*messageGherkinStep `json:"steps"`
+	Examples     []*messageExamples    `json:"examples"`
+	ID           string                `json:"id"`
+}
+
+type messageBackground struct {
+	Location     *messageLocation      `json:"location"`
+	Keyword      string                `json:"keyword"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Steps        []*messageGherkinStep `json:"steps"`
+	ID           string                `json:"id"`
+}
+
+type messageFeatureChild struct {
+	Background   *messageBackground    `json:"background,omitempty"`
+	Scenario     *messageScenario      `json:"scenario,omitempty"`
+}
+
+type messageFeature struct {
+	Location     *messageLocation        `json:"location"`
+	Tags         []*messageTag           `json:"tags"`
+	Language     string                  `json:"language"`
+	Keyword      string                  `json:"keyword"`
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description"`
+	Children     []*messageFeatureChild  `json:"children"`
+}
+
+type messageGherkinDocument struct {
+	URI          string                `json:"uri"`
+	Feature      *messageFeature       `json:"feature"`
+	Comments     []*messageComment     `json:"comments"`
+}
+
+type messageStepDefinitionPattern struct {
+	Source       string                `json:"source"`
+	Type         string                `json:"type"`
+}
+
+type messageSourceReference struct {
+	URI          string                `json:"uri,omitempty"`
+	Location     *messageLocation      `json:"location,omitempty"`
+}
+
+type messageStepDefinition struct {
+	ID               string                        `json:"id"`
+	Pattern          *messageStepDefinitionPattern `json:"pattern"`
+	SourceReference  *messageSourceReference       `json:"sourceReference"`
+}
+
+type messageTableCell struct {
+	Value        string                `json:"value"`
+}
+
+type messageTableRow struct {
+	Cells        []*messageTableCell   `json:"cells"`
+}
+
+type messageDataTable struct {
+	Rows         []*messageTableRow    `json:"rows"`
+}
+
+type messageDocString struct {
+	MediaType    string                `json:"mediaType,omitempty"`
+	Content      string                `json:"content"`
+}
+
+type messageStepArgument struct {
+	DataTable    *messageDataTable     `json:"dataTable,omitempty"`
+	DocString    *messageDocString     `json:"docString,omitempty"`
+}
+
+type messagePickleStep struct {
+	ID           string                `json:"id"`
+	Text         string                `json:"text"`
+	AstNodeIds   []string              `json:"astNodeIds"`
+	Argument     *messageStepArgument  `json:"argument,omitempty"`
+}
+
+type messagePickleTag struct {
+	Name         string                `json:"name"`
+	AstNodeId    string                `json:"astNodeId"`
+}
+
+type messagePickle struct {
+	ID           string                `json:"id"`
+	URI          string                `json:"uri"`
+	Name         string                `json:"name"`
+	Language     string                `json:"language"`
+	Steps        []*messagePickleStep  `json:"steps"`
+	Tags         []*messagePickleTag   `json:"tags"`
+	AstNodeIds   []string              `json:"astNodeIds"`
+}
+
+type messageTestStep struct {
+	ID                string           `json:"id"`
+	PickleStepId      string           `json:"pickleStepId"`
+	StepDefinitionIds []string         `json:"stepDefinitionIds"`
+}
+
+type messageTestCase struct {
+	ID           string                `json:"id"`
+	PickleId     string                `json:"pickleId"`
+	TestSteps    []*messageTestStep    `json:"testSteps"`
+}
+
+type messageTestRunStarted struct {
+	Timestamp    *messageTimestamp     `json:"timestamp"`
+}
+
+type messageTestCaseStarted struct {
+	ID           string                `json:"id"`
+	TestCaseId   string                `json:"testCaseId"`
+	Attempt      int                   `json:"attempt"`
+	Timestamp    *messageTimestamp     `json:"timestamp"`
+}
+
+type messageTestStepStarted struct {
+	TestCaseStartedId string           `json:"testCaseStartedId"`
+	TestStepId        string           `json:"testStepId"`
+	Timestamp         *messageTimestamp `json:"timestamp"`
+}
+
+type messageTestStepResult struct {
+	Status       string                `json:"status"`
+	Duration     *messageDuration      `json:"duration"`
+	Message      string                `json:"message,omitempty"`
+}
+
+type messageTestStepFinished struct {
+	TestCaseStartedId string                 `json:"testCaseStartedId"`
+	TestStepId        string                 `json:"testStepId"`
+	TestStepResult    *messageTestStepResult `json:"testStepResult"`
+	Timestamp         *messageTimestamp      `json:"timestamp"`
+}
+
+type messageAttachment struct {
+	TestCaseStartedId string           `json:"testCaseStartedId"`
+	TestStepId        string           `json:"testStepId"`
+	Body              []byte           `json:"body"`
+	ContentEncoding   string           `json:"contentEncoding"`
+	MediaType         string           `json:"mediaType"`
+}
+
+type messageTestCaseFinished struct {
+	TestCaseStartedId string           `json:"testCaseStartedId"`
+	Timestamp         *messageTimestamp `json:"timestamp"`
+	WillBeRetried     bool             `json:"willBeRetried"`
+}
+
+type messageTestRunFinished struct {
+	Success      bool                  `json:"success"`
+	Timestamp    *messageTimestamp     `json:"timestamp"`
+}
+
+type messageEnvelope struct {
+	Meta              *messageMeta              `json:"meta,omitempty"`
+	Source            *messageSource            `json:"source,omitempty"`
+	GherkinDocument   *messageGherkinDocument   `json:"gherkinDocument,omitempty"`
+	Pickle            *messagePickle            `json:"pickle,omitempty"`
+	StepDefinition    *messageStepDefinition    `json:"stepDefinition,omitempty"`
+	TestRunStarted    *messageTestRunStarted    `json:"testRunStarted,omitempty"`
+	TestCase          *messageTestCase          `json:"testCase,omitempty"`
+	TestCaseStarted   *messageTestCaseStarted   `json:"testCaseStarted,omitempty"`
+	TestStepStarted   *messageTestStepStarted   `json:"testStepStarted,omitempty"`
+	Attachment        *messageAttachment        `json:"attachment,omitempty"`
+	TestStepFinished  *messageTestStepFinished  `json:"testStepFinished,omitempty"`
+	TestCaseFinished  *messageTestCaseFinished  `json:"testCaseFinished,omitempty"`
+	TestRunFinished   *messageTestRunFinished   `json:"testRunFinished,omitempty"`
+}
+
+// Cucumber Messages status of G2T_STATUS_*
+func messageStatus(status int) string {
+	return strings.ToUpper(cucumberStatus(status))
+}
+
+func newMessageLocation(location *ghk.Location) *messageLocation {
+	if location == nil {
+		return &messageLocation{}
+	}
+	return &messageLocation{Line: location.Line, Column: location.Column}
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: messageAst
+// Creates gherkinDocument messages, and keeps the ids of their nodes for pickles
+// @values
+//    - count: Number of ids given
+//    - steps: Id of every step
+//    - outlineSteps: Steps of Scenario Outline, their pickle steps are linked to the example row too
+//    - pickles: astNodeIds of scenario, by source and the line of scenario or example row
+//    - tags: Id of every tag name of scenario, by source and the line of scenario or example row
+// ----------------------------------------------------------------------------------
+type messageAst struct {
+	count         int
+	steps         map[*ghk.Step]string
+	outlineSteps  map[*ghk.Step]bool
+	pickles       map[*gherkinSource]map[int][]string
+	tags          map[*gherkinSource]map[int]map[string]string
+}
+
+func newMessageAst() *messageAst {
+	return &messageAst{
+		steps: make(map[*ghk.Step]string),
+		outlineSteps: make(map[*ghk.Step]bool),
+		pickles: make(map[*gherkinSource]map[int][]string),
+		tags: make(map[*gherkinSource]map[int]map[string]string),
+	}
+}
+
+// New id of node
+func (v *messageAst) id() string {
+	v.count++
+	return fmt.Sprintf("ast-%d", v.count)
+}
+
+// Create gherkinDocument of source
+func (v *messageAst) document(source *gherkinSource) *messageGherkinDocument {
+	gFeature := source.document
+	v.pickles[source] = make(map[int][]string)
+	v.tags[source] = make(map[int]map[string]string)
+
+	featureTags := make(map[string]string)
+	feature := &messageFeature{
+		Location: newMessageLocation(gFeature.Location),
+		Tags: v.tagList(gFeature.Tags, featureTags),
+		Language: gherkinLanguage(gFeature),
+		Keyword: gFeature.Keyword,
+		Name: gFeature.Name,
+		Description: gFeature.Description,
+		Children: make([]*messageFeatureChild, 0),
+	}
+	if bg := gFeature.Background; bg != nil {
+		feature.Children = append(feature.Children, &messageFeatureChild{Background: &messageBackground{
+			Location: newMessageLocation(bg.Location),
+			Keyword: bg.Keyword,
+			Name: bg.Name,
+			Description: bg.Description,
+			Steps: v.stepList(bg.Steps, false),
+			ID: v.id(),
+		}})
+	}
+
+	for _, s := range gFeature.ScenarioDefinitions {
+		tags := copyTagIds(featureTags)
+		scenario := &messageScenario{Examples: make([]*messageExamples, 0)}
+		switch gScenario := s.(type) {
+		case *ghk.Scenario:
+			scenario.Location = newMessageLocation(gScenario.Location)
+			scenario.Tags = v.tagList(gScenario.Tags, tags)
+			scenario.Keyword = gScenario.Keyword
+			scenario.Name = gScenario.Name
+			scenario.Description = gScenario.Description
+			scenario.Steps = v.stepList(gScenario.Steps, false)
+			scenario.ID = v.id()
+			v.pickles[source][lineOf(gScenario.Location)] = []string{scenario.ID}
+			v.tags[source][lineOf(gScenario.Location)] = tags
+		case *ghk.ScenarioOutline:
+			scenario.Location = newMessageLocation(gScenario.Location)
+			scenario.Tags = v.tagList(gScenario.Tags, tags)
+			scenario.Keyword = gScenario.Keyword
+			scenario.Name = gScenario.Name
+			scenario.Description = gScenario.Description
+			scenario.Steps = v.stepList(gScenario.Steps, true)
+			scenario.ID = v.id()
+			for _, gExamples := range gScenario.Examples {
+				exampleTags := copyTagIds(tags)
+				examples := &messageExamples{
+					Location: newMessageLocation(gExamples.Location),
+					Tags: v.tagList(gExamples.Tags, exampleTags),
+					Keyword: gExamples.Keyword,
+					Name: gExamples.Name,
+					Description: gExamples.Description,
+					TableBody: v.rowList(gExamples.TableBody),
+				}
+				if gExamples.TableHeader != nil {
+					examples.TableHeader = v.rowList([]*ghk.TableRow{gExamples.TableHeader})[0]
+				}
+				examples.ID = v.id()
+				for _, row := range examples.TableBody {
+					v.pickles[source][row.Location.Line] = []string{scenario.ID, row.ID}
+					v.tags[source][row.Location.Line] = exampleTags
+				}
+				scenario.Examples = append(scenario.Examples, examples)
+			}
+		}
+		feature.Children = append(feature.Children, &messageFeatureChild{Scenario: scenario})
+	}
+
+	comments := make([]*messageComment, 0, len(gFeature.Comments))
+	for _, comment := range gFeature.Comments {
+		comments = append(comments, &messageComment{Location: newMessageLocation(comment.Location), Text: comment.Text})
+	}
+	return &messageGherkinDocument{URI: source.uri, Feature: feature, Comments: comments}
+}
+
+// Create tag messages, and keep their ids by name
+func (v *messageAst) tagList(gTags []*ghk.Tag, ids map[string]string) []*messageTag {
+	tags := make([]*messageTag, 0, len(gTags))
+	for _, gTag := range gTags {
+		tag := &messageTag{Location: newMessageLocation(gTag.Location), Name: gTag.Name, ID: v.id()}
+		ids[tag.Name] = tag.ID
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Create step messages, and keep their ids
+func (v *messageAst) stepList(gSteps []*ghk.Step, outline bool) []*messageGherkinStep {
+	steps := make([]*messageGherkinStep, 0, len(gSteps))
+	for _, gStep := range gSteps {
+		step := &messageGherkinStep{
+			Location: newMessageLocation(gStep.Location),
+			Keyword: gStep.Keyword,
+			Text: gStep.Text,
+		}
+		switch argument := gStep.Argument.(type) {
+		case *ghk.DataTable:
+			step.DataTable = &messageGherkinDataTable{
+				Location: newMessageLocation(argument.Location),
+				Rows: v.rowList(argument.Rows),
+			}
+		case *ghk.DocString:
+			step.DocString = &messageGherkinDocString{
+				Location: newMessageLocation(argument.Location),
+				MediaType: argument.ContentType,
+				Content: argument.Content,
+				Delimiter: argument.Delimitter,
+			}
+		}
+		step.ID = v.id()
+		v.steps[gStep] = step.ID
+		v.outlineSteps[gStep] = outline
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// Create table row messages
+func (v *messageAst) rowList(gRows []*ghk.TableRow) []*messageGherkinTableRow {
+	rows := make([]*messageGherkinTableRow, 0, len(gRows))
+	for _, gRow := range gRows {
+		row := &messageGherkinTableRow{
+			Location: newMessageLocation(gRow.Location),
+			Cells: make([]*messageGherkinTableCell, 0, len(gRow.Cells)),
+			ID: v.id(),
+		}
+		for _, cell := range gRow.Cells {
+			row.Cells = append(row.Cells, &messageGherkinTableCell{Location: newMessageLocation(cell.Location), Value: cell.Value})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// astNodeIds of pickle step: the step, and the example row if it's a step of Scenario Outline
+func (v *messageAst) stepIds(step *Step, pickleIds []string) []string {
+	ids := make([]string, 0, 2)
+	if id, ok := v.steps[step.gherkin]; ok {
+		ids = append(ids, id)
+		if v.outlineSteps[step.gherkin] && len(pickleIds) > 1 {
+			ids = append(ids, pickleIds[1])
+		}
+	}
+	return ids
+}
+
+// Language of feature, "en" if not given
+func gherkinLanguage(gFeature *ghk.Feature) string {
+	if gFeature.Language == "" {
+		return "en"
+	}
+	return gFeature.Language
+}
+
+func copyTagIds(ids map[string]string) map[string]string {
+	ret := make(map[string]string, len(ids))
+	for name, id := range ids {
+		ret[name] = id
+	}
+	return ret
+}
+
+
+// Create stepDefinition message of action
+func newMessageStepDefinition(id string, action *stepAction) *messageStepDefinition {
+	pattern := &messageStepDefinitionPattern{Source: action.text, Type: "REGULAR_EXPRESSION"}
+	if action.expression {
+		pattern.Type = "CUCUMBER_EXPRESSION"
+	}
+	reference := new(messageSourceReference)
+	if pos := strings.LastIndex(action.location, ":"); pos > 0 {
+		line, _ := strconv.Atoi(action.location[pos+1:])
+		reference.URI = action.location[:pos]
+		reference.Location = &messageLocation{Line: line}
+	}
+	return &messageStepDefinition{ID: id, Pattern: pattern, SourceReference: reference}
+}
+
+
+// Write the result as Cucumber Messages, one JSON envelope per line
+// Pickles, test steps and step definitions are linked to the gherkinDocument by ids
+// @params:
+//    w: Where the NDJSON goes
+// @returns:
+//    (error): Errors of encoding or writing
+func (v *Result) WriteMessages(w io.Writer) error {
+	envelopes := make([]*messageEnvelope, 0)
+	add := func(e *messageEnvelope) {
+		envelopes = append(envelopes, e)
+	}
+
+	add(&messageEnvelope{Meta: &messageMeta{
+		ProtocolVersion: messagesProtocolVersion,
+		Implementation: &messageProduct{Name: "go2test"},
+		Runtime: &messageProduct{Name: "go", Version: runtime.Version()},
+		Os: &messageProduct{Name: runtime.GOOS},
+		Cpu: &messageProduct{Name: runtime.GOARCH},
+	}})
+
+	// Hook files go first, steps of features may come from them
+	ast := newMessageAst()
+	addSource := func(source *gherkinSource) {
+		add(&messageEnvelope{Source: &messageSource{
+			URI: source.uri,
+			Data: source.data,
+			MediaType: "text/x.cucumber.gherkin+plain",
+		}})
+		add(&messageEnvelope{GherkinDocument: ast.document(source)})
+	}
+	for _, source := range v.hookSources {
+		addSource(source)
+	}
+
+	// Step definitions are written after pickles, every action added has one
+	definitionIds := make(map[*stepAction]string)
+	for idx, action := range v.actions {
+		definitionIds[action] = fmt.Sprintf("stepdef-%d", idx)
+	}
+
+	// Pickles, ids are built from the position in the result
+	testCases := make([]*messageTestCase, 0)
+	for fid, feature := range v.Features {
+		if feature.source != nil {
+			addSource(feature.source)
+		}
+		for sid, scenario := range feature.Scenarios {
+			prefix := fmt.Sprintf("%d-%d", fid, sid)
+			pickle := &messagePickle{
+				ID: "pickle-" + prefix,
+				URI: feature.Path,
+				Name: scenario.Name,
+				Language: "en",
+				Steps: make([]*messagePickleStep, 0, len(scenario.Steps)),
+				Tags: make([]*messagePickleTag, 0, len(scenario.Tags)),
+				AstNodeIds: []string{},
+			}
+			if feature.source != nil {
+				pickle.Language = gherkinLanguage(feature.source.document)
+				if ids, ok := ast.pickles[feature.source][scenario.Line]; ok {
+					pickle.AstNodeIds = ids
+				}
+			}
+			tagIds := ast.tags[feature.source][scenario.Line]
+			for _, tag := range scenario.Tags {
+				pickle.Tags = append(pickle.Tags, &messagePickleTag{Name: tag, AstNodeId: tagIds[tag]})
+			}
+			testCase := &messageTestCase{
+				ID: "testcase-" + prefix,
+				PickleId: pickle.ID,
+				TestSteps: make([]*messageTestStep, 0, len(scenario.Steps)),
+			}
+			for stid, step := range scenario.AllSteps() {
+				ps := &messagePickleStep{
+					ID: fmt.Sprintf("pickle-%s-%d", prefix, stid),
+					Text: step.Text,
+					AstNodeIds: ast.stepIds(step, pickle.AstNodeIds),
+				}
+				if step.Rows != nil {
+					table := &messageDataTable{Rows: make([]*messageTableRow, 0, len(step.Rows))}
+					for _, row := range step.Rows {
+						r := &messageTableRow{Cells: make([]*messageTableCell, 0, len(row))}
+						for _, cell := range row {
+							r.Cells = append(r.Cells, &messageTableCell{Value: cell})
+						}
+						table.Rows = append(table.Rows, r)
+					}
+					ps.Argument = &messageStepArgument{DataTable: table}
+				}
+				if step.DocString != nil {
+					ps.Argument = &messageStepArgument{DocString: &messageDocString{
+						MediaType: step.DocString.ContentType,
+						Content: step.DocString.Content,
+					}}
+				}
+				pickle.Steps = append(pickle.Steps, ps)
+
+				// Undefined and ambiguous steps have no action
+				testStep := &messageTestStep{
+					ID: fmt.Sprintf("teststep-%s-%d", prefix, stid),
+					PickleStepId: ps.ID,
+					StepDefinitionIds: []string{},
+				}
+				if id, ok := definitionIds[step.action]; ok {
+					testStep.StepDefinitionIds = append(testStep.StepDefinitionIds, id)
+				}
+				testCase.TestSteps = append(testCase.TestSteps, testStep)
+			}
+			add(&messageEnvelope{Pickle: pickle})
+			testCases = append(testCases, testCase)
+		}
+	}
+
+	for _, action := range v.actions {
+		add(&messageEnvelope{StepDefinition: newMessageStepDefinition(definitionIds[action], action)})
+	}
+
+	add(&messageEnvelope{TestRunStarted: &messageTestRunStarted{Timestamp: newMessageTimestamp(v.StartTime)}})
+	for _, testCase := range testCases {
+		add(&messageEnvelope{TestCase: testCase})
+	}
+
+	for fid, feature := range v.Features {
+		for sid, scenario := range feature.Scenarios {
+			prefix := fmt.Sprintf("%d-%d", fid, sid)
+			startedID := "testcasestarted-" + prefix
+			add(&messageEnvelope{TestCaseStarted: &messageTestCaseStarted{
+				ID: startedID,
+				TestCaseId: "testcase-" + prefix,
+				Timestamp: newMessageTimestamp(scenario.StartTime),
+			}})
+			for stid, step := range scenario.AllSteps() {
+				stepID := fmt.Sprintf("teststep-%s-%d", prefix, stid)
+				startTime := step.StartTime
+				if startTime.IsZero() {
+					startTime = scenario.StartTime.Add(scenario.Duration)
+				}
+				add(&messageEnvelope{TestStepStarted: &messageTestStepStarted{
+					TestCaseStartedId: startedID,
+					TestStepId: stepID,
+					Timestamp: newMessageTimestamp(startTime),
+				}})
+				for _, attachment := range step.Attachments {
+					add(&messageEnvelope{Attachment: &messageAttachment{
+						TestCaseStartedId: startedID,
+						TestStepId: stepID,
+						Body: attachment.Data,
+						ContentEncoding: "BASE64",
+						MediaType: attachment.MimeType,
+					}})
+				}
+				stepResult := &messageTestStepResult{
+					Status: messageStatus(step.Status),
+					Duration: newMessageDuration(step.Duration),
+				}
+				if step.Exception != nil {
+					stepResult.Message = step.Exception.Message
+				}
+				add(&messageEnvelope{TestStepFinished: &messageTestStepFinished{
+					TestCaseStartedId: startedID,
+					TestStepId: stepID,
+					TestStepResult: stepResult,
+					Timestamp: newMessageTimestamp(startTime.Add(step.Duration)),
+				}})
+			}
+			add(&messageEnvelope{TestCaseFinished: &messageTestCaseFinished{
+				TestCaseStartedId: startedID,
+				Timestamp: newMessageTimestamp(scenario.StartTime.Add(scenario.Duration)),
+			}})
+		}
+	}
+
+	add(&messageEnvelope{TestRunFinished: &messageTestRunFinished{
+		Success: !v.Failed(),
+		Timestamp: newMessageTimestamp(v.StartTime.Add(v.Duration)),
+	}})
+
+	encoder := json.NewEncoder(w)
+	for _, envelope := range envelopes {
+		if err := encoder.Encode(envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package go2test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------------------------
+// @name: DataTable
+// Data table of step, the first row is header
+// Actions can accept DataTable or *DataTable instead of []string or []map[string]string
+// Please use NewDataTable() to create new DataTable
+// ----------------------------------------------------------------------------------
+type DataTable struct {
+	raw         [][]string
+}
+
+var dataTableType = reflect.TypeOf(DataTable{})
+var dataTablePtrType = reflect.TypeOf(&DataTable{})
+
+// Create new *DataTable
+// @params:
+//    rows: Cells of table, the first row is header
+// @returns:
+//    (*DataTable): new *DataTable, rows are copied
+func NewDataTable(rows [][]string) *DataTable {
+	v := new(DataTable)
+	v.raw = make([][]string, 0, len(rows))
+	for _, row := range rows {
+		v.raw = append(v.raw, append([]string{}, row...))
+	}
+	return v
+}
+
+// All rows, include the header
+func (v *DataTable) Raw() [][]string {
+	return v.raw
+}
+
+// Cells of the first row, in order
+func (v *DataTable) Headers() []string {
+	if len(v.raw) == 0 {
+		return []string{}
+	}
+	return v.raw[0]
+}
+
+// Rows under the header
+func (v *DataTable) Rows() [][]string {
+	if len(v.raw) == 0 {
+		return [][]string{}
+	}
+	return v.raw[1:]
+}
+
+// Swap rows and columns, e.g. a key/value table becomes a table with one row
+// @returns:
+//    (*DataTable): new *DataTable
+func (v *DataTable) Transpose() *DataTable {
+	columns := 0
+	for _, row := range v.raw {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	rows := make([][]string, columns)
+	for col := range rows {
+		rows[col] = make([]string, len(v.raw))
+		for idx, row := range v.raw {
+			if col < len(row) {
+				rows[col][idx] = row[col]
+			}
+		}
+	}
+	return &DataTable{raw: rows}
+}
+
+// Rows under the header as maps, header => cell
+func (v *DataTable) AsMaps() []map[string]string {
+	headers := v.Headers()
+	ret := make([]map[string]string, 0, len(v.Rows()))
+	for _, row := range v.Rows() {
+		r := make(map[string]string)
+		for idx, cell := range row {
+			if idx < len(headers) {
+				r[headers[idx]] = cell
+			}
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// Cell under the header
+// @params:
+//    row: Index of row under the header, from 0
+//    col: Index of column, from 0
+// @returns:
+//    (string): Text of cell, empty if out of range
+func (v *DataTable) Cell(row int, col int) string {
+	rows := v.Rows()
+	if row < 0 || row >= len(rows) || col < 0 || col >= len(rows[row]) {
+		return ""
+	}
+	return rows[row][col]
+}
+
+// Cell under the header, by the header of column
+// @params:
+//    row: Index of row under the header, from 0
+//    header: Header of column
+// @returns:
+//    (string): Text of cell, empty if not found
+func (v *DataTable) Value(row int, header string) string {
+	for col, h := range v.Headers() {
+		if h == header {
+			return v.Cell(row, col)
+		}
+	}
+	return ""
+}
+
+// Cell converted to int
+func (v *DataTable) CellInt(row int, col int) (int, error) {
+	var ret int
+	err := v.CellAs(row, col, &ret)
+	return ret, err
+}
+
+// Cell converted to float64
+func (v *DataTable) CellFloat(row int, col int) (float64, error) {
+	var ret float64
+	err := v.CellAs(row, col, &ret)
+	return ret, err
+}
+
+// Cell converted to bool
+func (v *DataTable) CellBool(row int, col int) (bool, error) {
+	var ret bool
+	err := v.CellAs(row, col, &ret)
+	return ret, err
+}
+
+// Convert cell into the type of ptr, like params of action
+// @params:
+//    row: Index of row under the header, from 0
+//    col: Index of column, from 0
+//    ptr: Pointer to value, e.g. *time.Duration
+// @returns:
+//    (error): Conversion error
+func (v *DataTable) CellAs(row int, col int, ptr interface{}) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("expect pointer, got %T", ptr)
+	}
+	converted, err := convertParam(v.Cell(row, col), value.Elem().Type())
+	if err != nil {
+		return fmt.Errorf("cell (%d, %d): %s", row, col, err.Error())
+	}
+	value.Elem().Set(converted)
+	return nil
+}
+
+// Compare with the actual table, use it to assert data
+// @params:
+//    actual: The actual table
+// @returns:
+//    (error): nil if same, otherwise the diff in table, "-" for expected and "+" for actual rows
+func (v *DataTable) Diff(actual *DataTable) error {
+	expected := v.raw
+	got := actual.raw
+
+	// Longest common rows
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			switch {
+			case equalRow(expected[i], got[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	marks := make([]string, 0, len(expected)+len(got))
+	rows := make([][]string, 0, len(expected)+len(got))
+	different := false
+	i, j := 0, 0
+	for i < len(expected) || j < len(got) {
+		switch {
+		case i < len(expected) && j < len(got) && equalRow(expected[i], got[j]):
+			marks = append(marks, " ")
+			rows = append(rows, expected[i])
+			i++
+			j++
+		case j >= len(got) || (i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			marks = append(marks, "-")
+			rows = append(rows, expected[i])
+			different = true
+			i++
+		default:
+			marks = append(marks, "+")
+			rows = append(rows, got[j])
+			different = true
+			j++
+		}
+	}
+	if !different {
+		return nil
+	}
+
+	lines := []string{"Tables are different:"}
+	for idx, line := range formatTable(rows) {
+		lines = append(lines, marks[idx]+" "+line)
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// Aligned table, like in *.feature
+func (v *DataTable) String() string {
+	return strings.Join(formatTable(v.raw), "\n")
+}
+
+func equalRow(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// Format rows as "| a | b |", cells of a column have the same width
+func formatTable(rows [][]string) []string {
+	widths := make([]int, 0)
+	for _, row := range rows {
+		for col, cell := range row {
+			if col >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[col] {
+				widths[col] = n
+			}
+		}
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		line := "|"
+		for col, cell := range row {
+			line += " " + cell + strings.Repeat(" ", widths[col]-utf8.RuneCountInString(cell)) + " |"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,74 @@
+package go2test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ----------------------------------------------------------------------------------
+// @name: DocString
+// Text block of step between """ or ```
+// @values
+//    - ContentType: Type after the delimiter, e.g. json, empty if not given
+//    - Content: The text
+// ----------------------------------------------------------------------------------
+type DocString struct {
+	ContentType  string
+	Content      string
+}
+
+var docStringType = reflect.TypeOf(DocString{})
+var docStringPtrType = reflect.TypeOf(&DocString{})
+var anyMapType = reflect.TypeOf(map[string]interface{}{})
+
+// Whether the type can receive the step's DocString
+// string, DocString, *DocString, or struct, *struct, map[string]interface{} unmarshalled from json/yaml
+func isDocStringType(typ reflect.Type) bool {
+	switch {
+	case typ.Kind() == reflect.String, typ == docStringType, typ == docStringPtrType, typ == anyMapType:
+		return true
+	case typ.Kind() == reflect.Struct:
+		return true
+	case typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// Convert DocString into the type declared by action
+// @params:
+//    doc: DocString of step
+//    typ: Type of action's param
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Unmarshal error, or unknown content type
+func convertDocString(doc *DocString, typ reflect.Type) (reflect.Value, error) {
+	switch {
+	case typ == docStringType:
+		return reflect.ValueOf(*doc), nil
+	case typ == docStringPtrType:
+		return reflect.ValueOf(doc), nil
+	case typ.Kind() == reflect.String:
+		return reflect.ValueOf(doc.Content).Convert(typ), nil
+	}
+
+	ptr := reflect.New(typ)
+	var err error
+	switch strings.ToLower(doc.ContentType) {
+	case "json":
+		err = json.Unmarshal([]byte(doc.Content), ptr.Interface())
+	case "yaml", "yml":
+		err = yaml.Unmarshal([]byte(doc.Content), ptr.Interface())
+	default:
+		return ptr.Elem(), fmt.Errorf("cannot unmarshal DocString of content type [%s] into %s, expect json or yaml",
+			doc.ContentType, typ)
+	}
+	if err != nil {
+		return ptr.Elem(), err
+	}
+	return ptr.Elem(), nil
+}
@@ -0,0 +1,23 @@
+Feature: Cleanup
+
+  Scenario: @after(1) / ^Clean
+    Given Log Close connection
+
+  Scenario: @after(2) / ^Clean
+    Given Log Delete user
+    And Fail cleanup
+
+  Scenario: Clean failed
+    Given Log Create user
+    And Fail step
+    And Log Never
+
+  Scenario: Clean passed
+    Given Log Create user
+
+  Scenario Outline: Clean outline
+    Given Log <name>
+
+    Examples:
+      | name |
+      | Tom  |
@@ -0,0 +1,8 @@
+Feature: Cleanup after timeout
+
+  Scenario: @after(1) / .*
+    Given Read result
+
+  @timeout(50ms)
+  Scenario: Slow scenario
+    Given Write result slowly
@@ -0,0 +1,11 @@
+Feature: DataTable
+
+  Scenario: DataTable
+    Given Prices
+      | Item   | Price | Stock |
+      | Apple  | 1.5   | true  |
+      | Banana | 0.25  | false |
+    Then Expect
+      | Item   | Price |
+      | Apple  | 1.5   |
+      | Banana | 0.25  |
@@ -0,0 +1,24 @@
+Feature: DocString
+
+  Scenario: DocString
+    Given Text
+      """
+      Hello <name>
+      """
+    And Document
+      """markdown
+      # Title
+      """
+    And User in json
+      """json
+      {"name": "Tom", "age": 30}
+      """
+    And User in yaml
+      """yaml
+      name: Jerry
+      age: 3
+      """
+    And Map in json
+      """json
+      {"name": "Tom", "admin": true}
+      """
@@ -0,0 +1,7 @@
+Feature: Cucumber Expressions
+
+  Scenario: Expressions
+    Given Person1's name: "Tom Smith"
+    And Tom has 3 cucumbers in the basket
+    And Tom has 1 cucumber on the basket
+    And the ratio is -0.5
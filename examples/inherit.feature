@@ -0,0 +1,24 @@
+@Smoke
+Feature: Tag Inheritance
+
+  Scenario: Untagged
+    Given Name Untagged
+
+  @Slow
+  Scenario: Slow
+    Given Name Slow
+
+  @Outline
+  Scenario Outline: Outline
+    Given Name: <NAME>
+
+    @Fast
+    Examples: Fast List
+      |NAME|
+      |Tom |
+
+    @Slow
+    Examples: Slow List
+      |NAME|
+      |Eric|
+      |Mike|
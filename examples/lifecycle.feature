@@ -0,0 +1,10 @@
+@api
+Feature: Lifecycle
+
+  Scenario: First
+    Given Log one
+    And Log two
+
+  @db
+  Scenario: Second
+    Given Log three
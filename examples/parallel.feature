@@ -0,0 +1,21 @@
+Feature: Parallel
+
+  Background:
+    Given Remember P0
+
+  Scenario: P1
+    Given Remember P1
+    Then Wait and check P1
+
+  Scenario: P2
+    Given Remember P2
+    Then Wait and check P2
+
+  Scenario: P3
+    Given Remember P3
+    Then Wait and check P3
+
+  @serial
+  Scenario: P4
+    Given Remember P4
+    Then Wait and check P4
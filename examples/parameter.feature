@@ -0,0 +1,5 @@
+Feature: Parameter Types
+
+  Scenario: Parameter Types
+    Given Tom pays $12.50 on 2024-02-29
+    And Tom is a member since 2020-01-01
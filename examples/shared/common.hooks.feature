@@ -0,0 +1,13 @@
+Feature: Common hooks
+
+  Background:
+    Given Log Shared background
+
+  Scenario: @before(1) / .*
+    Given Log Shared before(1)
+
+  Scenario: @before(3) / tag:@db
+    Given Log Shared before(3)
+
+  Scenario: @after(1) / .*
+    Given Log Shared after(1)
@@ -0,0 +1,4 @@
+Feature: Extra hooks
+
+  Scenario: @after(2) / .*
+    Given Log Extra after(2)
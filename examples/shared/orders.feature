@@ -0,0 +1,17 @@
+Feature: Orders
+
+  Background:
+    Given Log Orders background
+
+  Scenario: @before(1) / .*
+    Given Log Orders before(1)
+
+  Scenario: @before(2) / .*
+    Given Log Orders before(2)
+
+  Scenario: @after(1) / .*
+    Given Log Orders after(1)
+
+  @db
+  Scenario: Create order
+    Given Log Create order
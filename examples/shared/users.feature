@@ -0,0 +1,4 @@
+Feature: Users
+
+  Scenario: List users
+    Given Log List users
@@ -0,0 +1,11 @@
+Feature: Struct Tables
+
+  Scenario: Struct Tables
+    Given Users
+      | Title1 | First name | Age | Admin |
+      | Mr     | Tom        | 30  | true  |
+      | Ms     | Ann        | 25  | false |
+    And Account
+      | Owner   | Tom  |
+      | Balance | 12.5 |
+      | Expire  | 1h   |
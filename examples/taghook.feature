@@ -0,0 +1,31 @@
+@api
+Feature: Tag hooks
+
+  Scenario: @before(1) / tag:@db and not @readonly
+    Given Log Open write connection
+
+  Scenario: @before(2) / tag:@db
+    Given Log Load fixtures
+
+  Scenario: @after(1) / tag:@api
+    Given Log Close api
+
+  @db
+  Scenario: Write
+    Given Log Write row
+
+  @db @readonly
+  Scenario: Read
+    Given Log Read row
+
+  Scenario Outline: Outline <name>
+    Given Log Use <name>
+
+    @db
+    Examples:
+      | name  |
+      | db    |
+
+    Examples:
+      | name  |
+      | plain |
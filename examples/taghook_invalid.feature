@@ -0,0 +1,7 @@
+Feature: Invalid tag hook
+
+  Scenario: @before / tag:@db and 100%
+    Given Log Never
+
+  Scenario: Any
+    Given Log Any
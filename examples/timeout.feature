@@ -0,0 +1,12 @@
+Feature: Timeout
+
+  Scenario: Step Timeout
+    Given Sleep 10ms
+    Given Sleep 1s
+    Given Sleep 10ms
+
+  @timeout(100ms)
+  Scenario: Scenario Timeout
+    Given Sleep 60ms
+    Given Sleep 60ms
+    Given Sleep 10ms
@@ -0,0 +1,4 @@
+Feature: Typed Params
+
+  Scenario: Typed
+    Given Count 3, ratio 0.5, enabled true, wait 1m30s
@@ -0,0 +1,236 @@
+package go2test
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------------
+// @name: parameterType
+// Parameter of Cucumber Expression, e.g. {int}, or a type registered by AddParameterType()
+// @values
+//    - name: Name between the braces
+//    - regex: Regex of the text, must not have capture groups
+//    - typ: Type of value, passed to interface{} params too. nil means string
+//    - transform: Change the matched text before conversion, e.g. remove quotes
+//    - transformer: func(string) T or func(string) (T, error) registered by AddParameterType()
+// ----------------------------------------------------------------------------------
+type parameterType struct {
+	name        string
+	regex       string
+	typ         reflect.Type
+	transform   func(string) string
+	transformer reflect.Value
+}
+
+// Whether the value can be passed to the param of action
+// @params:
+//    typ: Type of action's param
+func (v *parameterType) accepts(typ reflect.Type) bool {
+	if v.transformer.IsValid() {
+		return v.typ.AssignableTo(typ)
+	}
+	return isConvertibleType(typ)
+}
+
+// Convert the matched text into the type of action's param
+// @params:
+//    text: Matched text
+//    typ: Type of action's param
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Conversion error
+func (v *parameterType) convert(text string, typ reflect.Type) (reflect.Value, error) {
+	if v.transform != nil {
+		text = v.transform(text)
+	}
+	if v.transformer.IsValid() {
+		outs := v.transformer.Call([]reflect.Value{reflect.ValueOf(text)})
+		if len(outs) == 2 && !outs[1].IsNil() {
+			return outs[0], outs[1].Interface().(error)
+		}
+		ret := reflect.New(typ).Elem()
+		ret.Set(outs[0])
+		return ret, nil
+	}
+	// e.g. {int} gives int to interface{}
+	if typ.Kind() == reflect.Interface && v.typ != nil && v.typ.AssignableTo(typ) {
+		value, err := convertParam(text, v.typ)
+		if err != nil {
+			return value, err
+		}
+		ret := reflect.New(typ).Elem()
+		ret.Set(value)
+		return ret, nil
+	}
+	return convertParam(text, typ)
+}
+
+// Create parameter type from transformer
+// @params:
+//    name: Name used in expression, e.g. "user" for {user}
+//    regex: Regex of the text, use (?:...) instead of capture groups
+//    transformer: func(string) T or func(string) (T, error)
+// @returns:
+//    (*parameterType): new parameter type
+//    (error): Invalid regex or transformer
+func newParameterType(name string, regex string, transformer interface{}) (*parameterType, error) {
+	if name == "" || strings.ContainsAny(name, "{}()\\/ ") {
+		return nil, fmt.Errorf("invalid name of parameter type [%s]", name)
+	}
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	if compiled.NumSubexp() > 0 {
+		return nil, fmt.Errorf("regex of {%s} must not have capture groups, use (?:...) instead", name)
+	}
+	value := reflect.ValueOf(transformer)
+	if value.Kind() != reflect.Func {
+		return nil, fmt.Errorf("transformer of {%s} must be a func, got %s", name, value.Kind())
+	}
+	valueType := value.Type()
+	if valueType.NumIn() != 1 || valueType.In(0).Kind() != reflect.String || valueType.IsVariadic() ||
+		valueType.NumOut() < 1 || valueType.NumOut() > 2 ||
+		(valueType.NumOut() == 2 && valueType.Out(1) != errorType) {
+		return nil, fmt.Errorf("transformer of {%s} must be func(string) T or func(string) (T, error), got %s",
+			name, valueType)
+	}
+	return &parameterType{name: name, regex: regex, typ: valueType.Out(0), transformer: value}, nil
+}
+
+// Built-in parameter types of Cucumber Expression
+var builtinParameterTypes = map[string]*parameterType{
+	"int":    {name: "int", regex: `-?\d+`, typ: reflect.TypeOf(int(0))},
+	"float":  {name: "float", regex: `-?\d*\.?\d+`, typ: reflect.TypeOf(float64(0))},
+	"word":   {name: "word", regex: `[^\s]+`},
+	"string": {name: "string", regex: `"[^"]*"|'[^']*'`, transform: unquote},
+	"":       {name: "", regex: `.*`},
+}
+
+// Remove the quotes around {string}
+func unquote(text string) string {
+	if len(text) >= 2 {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+
+// Compile Cucumber Expression into regex
+// Supports {parameter}, optional text "cucumber(s)", alternative text "in/on" and escapes "\{"
+// "/" without text on one side is not alternative, it matches "/"
+// @params:
+//    expr: e.g. "{word}'s name: {string}"
+//    types: Parameter types by name
+// @returns:
+//    (*regexp.Regexp): Regex with a capture group for each parameter
+//    ([]*parameterType): Parameter of each capture group
+//    (error): Syntax error or undefined parameter type
+func compileExpression(expr string, types map[string]*parameterType) (*regexp.Regexp, []*parameterType, error) {
+	params := make([]*parameterType, 0)
+	regex := "^"
+	text := ""  // literal text, an alternative word goes on until space
+
+	// Text without space may have alternatives, e.g. "in/on"
+	// "/" without text on one side is literal, e.g. "{int}/{int}" or "a/ b"
+	flush := func() {
+		if text == "" {
+			return
+		}
+		parts := splitUnescaped(text, '/')
+		for _, part := range parts {
+			if part == "" {
+				parts = []string{text}
+				break
+			}
+		}
+		quoted := make([]string, 0, len(parts))
+		for _, part := range parts {
+			quoted = append(quoted, quoteExpressionText(part))
+		}
+		if len(quoted) > 1 {
+			regex += "(?:" + strings.Join(quoted, "|") + ")"
+		} else {
+			regex += quoted[0]
+		}
+		text = ""
+	}
+
+	for pos := 0; pos < len(expr); pos++ {
+		c := expr[pos]
+		switch {
+		case c == '\\' && pos+1 < len(expr):
+			text += expr[pos : pos+2]
+			pos++
+		case c == '{':
+			end := strings.IndexByte(expr[pos:], '}')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("missing } in [%s]", expr)
+			}
+			name := expr[pos+1 : pos+end]
+			param, ok := types[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("undefined parameter type {%s} in [%s]", name, expr)
+			}
+			flush()
+			regex += "(" + param.regex + ")"
+			params = append(params, param)
+			pos += end
+		case c == '(':
+			end := strings.IndexByte(expr[pos:], ')')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("missing ) in [%s]", expr)
+			}
+			optional := expr[pos+1 : pos+end]
+			if strings.ContainsAny(optional, "{(") {
+				return nil, nil, fmt.Errorf("parameter or optional text inside optional text [%s]", expr)
+			}
+			flush()
+			regex += "(?:" + quoteExpressionText(optional) + ")?"
+			pos += end
+		case c == ' ':
+			flush()
+			regex += " "
+		default:
+			text += string(c)
+		}
+	}
+	flush()
+
+	compiled, err := regexp.Compile(regex + "$")
+	if err != nil {
+		return nil, nil, err
+	}
+	return compiled, params, nil
+}
+
+// Quote literal text of expression, escaped chars lose the backslash
+func quoteExpressionText(text string) string {
+	ret := ""
+	for pos := 0; pos < len(text); pos++ {
+		if text[pos] == '\\' && pos+1 < len(text) {
+			pos++
+		}
+		ret += regexp.QuoteMeta(text[pos : pos+1])
+	}
+	return ret
+}
+
+// Split text by sep, but not the escaped ones
+func splitUnescaped(text string, sep byte) []string {
+	parts := make([]string, 0)
+	last := 0
+	for pos := 0; pos < len(text); pos++ {
+		switch text[pos] {
+		case '\\':
+			pos++
+		case sep:
+			parts = append(parts, text[last:pos])
+			last = pos + 1
+		}
+	}
+	return append(parts, text[last:])
+}
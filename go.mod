@@ -0,0 +1,11 @@
+module github.com/ykswang/biggertest-go
+
+go 1.15
+
+require (
+	github.com/Sirupsen/logrus v1.0.6
+	github.com/cucumber/gherkin-go v3.2.0+incompatible
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -1,7 +1,9 @@
 package go2test
 
 import (
-	"os"
+	"bytes"
+	"context"
+	"io/ioutil"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -9,6 +11,8 @@ import (
 	"strings"
 	"runtime"
 	"strconv"
+	"time"
+	"sync"
 
 	ghk "github.com/cucumber/gherkin-go"
 	log "github.com/Sirupsen/logrus"
@@ -19,6 +23,8 @@ const G2T_STATUS_WAIT = 0
 const G2T_STATUS_PASS = 1
 const G2T_STATUS_FAIL = 2
 const G2T_STATUS_SKIP = 3
+const G2T_STATUS_UNDEFINED = 4
+const G2T_STATUS_AMBIGUOUS = 5
 
 
 // ----------------------------------------------------------------------------------
@@ -30,6 +36,7 @@ const G2T_STATUS_SKIP = 3
 //    - Step: Error Step
 //    - Message: Error Message
 //    - Stack: StaceTrace of call tree
+//    - Cause: The error returned by action, nil if none
 // ----------------------------------------------------------------------------------
 type Exception struct {
 	Feature    *Feature
@@ -37,6 +44,34 @@ type Exception struct {
 	Step       *Step
 	Message    string
 	Stack      string
+	Cause      error
+}
+
+// *Exception is an error
+func (v *Exception) Error() string {
+	return v.Message
+}
+
+// The wrapped error, for errors.Is() && errors.As()
+func (v *Exception) Unwrap() error {
+	return v.Cause
+}
+
+// Where the error is
+// @returns:
+//    (string): "path:line" of the step, or the path of feature, empty if unknown
+func (v *Exception) Location() string {
+	path := ""
+	if v.Feature != nil {
+		path = v.Feature.Path
+	}
+	if v.Step != nil && v.Step.Path != "" {
+		path = v.Step.Path
+	}
+	if v.Step != nil && v.Step.Line > 0 {
+		return fmt.Sprintf("%s:%d", path, v.Step.Line)
+	}
+	return path
 }
 
 
@@ -52,14 +87,48 @@ type Handle struct {
 	Feature      *Feature
 	Scenario     *Scenario
 	Step         *Step
+	runner       *Go2Test
+	ctx          context.Context
+}
+
+// The context of current step, it's cancelled when the step or scenario timed out
+// Long running actions should stop when it's done
+// @returns:
+//    (context.Context): The context, never nil
+func (v *Handle) Context() context.Context {
+	if v.ctx == nil {
+		return context.Background()
+	}
+	return v.ctx
+}
+
+// Tags of the current scenario, nil if not in a scenario
+func (v *Handle) scenarioTags() []string {
+	if v.Scenario == nil {
+		return nil
+	}
+	return v.Scenario.Tags
 }
 
 // Clean the handle
-func (v Handle) clean() {
+func (v *Handle) clean() {
 	v.Buffer = make(map[string]interface{})
 	v.Feature = nil
 	v.Scenario = nil
 	v.Step = nil
+	v.ctx = nil
+}
+
+// Send event to all listeners of Go2Test
+// @params:
+//    event: func calls the listener
+func (v *Handle) notify(event func(listener Listener)) {
+	if v.runner == nil {
+		return
+	}
+	for _, listener := range v.runner.listeners {
+		event(listener)
+	}
 }
 
 // Create an *Exception and panic it
@@ -88,23 +157,97 @@ func (v *Handle) NewException(format string, a ...interface{}) *Exception {
 	return e
 }
 
+// Create an *Exception wraps the error
+// @params:
+//    err: The error
+// @returns:
+//    (*Exception): New *Exception with Cause, or err itself if it's an *Exception
+func (v *Handle) WrapError(err error) *Exception {
+	if e, ok := err.(*Exception); ok {
+		return e
+	}
+	e := v.NewException("%s", err.Error())
+	e.Cause = err
+	return e
+}
+
+// Turn the value of panic into *Exception
+// @params:
+//    err: Value of recover()
+// @returns:
+//    (*Exception): err itself if it's an *Exception, or wraps it
+func (v *Handle) toException(err interface{}) *Exception {
+	switch e := err.(type) {
+	case *Exception:
+		return e
+	case error:
+		return v.WrapError(e)
+	default:
+		return v.NewException("%+v", err)
+	}
+}
+
+// Attach data to the current step, reporters (e.g. Cucumber JSON) will embed it
+// @params:
+//    mimeType: MIME type of data, e.g. "text/plain", "image/png"
+//    data: Content of the attachment
+func (v *Handle) Attach(mimeType string, data []byte) {
+	if v.Step == nil {
+		return
+	}
+	v.Step.Attachments = append(v.Step.Attachments, &Attachment{MimeType: mimeType, Data: data})
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: Attachment
+// Data attached to a step by Handle.Attach()
+// @values
+//    - MimeType: MIME type of Data
+//    - Data: Content of the attachment
+// ----------------------------------------------------------------------------------
+type Attachment struct {
+	MimeType   string
+	Data       []byte
+}
+
 
 // ----------------------------------------------------------------------------------
 // @name: Step
 // If step failed, framework will skip the remaining steps which belong the same scenario
 // @returns:
 //     Id: The order ID
+//     Keyword: Given|When|Then|And|But
 //     Text: Statement of step, teh statement must cloud be matched by regex in step libs
+//     Path: The *.feature the step is written in, the hook file for shared hooks and background
+//     Line: Line number in *.feature
+//     Rows: Cells of the step's data table, nil if without data table
+//     DocString: The step's DocString, nil if without DocString
 //     Action: The callback
 //     Params: Params pass to callback
-//     Status: Result WAIT|PASS|FAIL|SKIP
+//     Status: Result WAIT|PASS|FAIL|SKIP|UNDEFINED|AMBIGUOUS
+//     Exception: The *Exception if step failed, or why it's undefined
+//     Attachments: Data attached by Handle.Attach()
+//     StartTime: When the step started
+//     Duration: How long the step took
 // ----------------------------------------------------------------------------------
 type Step struct {
 	Id           int
+	Keyword      string
 	Text         string
+	Path         string
+	Line         int
+	Rows         [][]string
+	DocString    *DocString
 	Action       reflect.Value
 	Params       []reflect.Value
 	Status       int
+	Exception    *Exception
+	Attachments  []*Attachment
+	StartTime    time.Time
+	Duration     time.Duration
+	action       *stepAction
+	gherkin      *ghk.Step
 }
 
 // Do the step, run step's action with params
@@ -113,66 +256,207 @@ type Step struct {
 //    handle: *Handle, it's created by Go2Test
 func (v *Step) Run(handle *Handle) {
 
+	v.StartTime = time.Now()
 	defer func(){
+		v.Duration = time.Since(v.StartTime)
 		if err:=recover(); err!=nil {
-			name := reflect.TypeOf(err).Name()
-			var exception *Exception
-			if name != "*Exception" {
-				exception = handle.NewException(fmt.Sprintf("%v+", err))
-			} else {
-				exception = err.(*Exception)
-			}
-			log.Errorf(" ")
-			log.Errorf("|    FAIL!!!")
-			log.Errorf("|    %s", exception.Message)
-			msgs := strings.Split(exception.Stack, "\n")
-			for _, msg := range msgs {
-				log.Errorf("|    %s ", msg)
-			}
-			log.Errorf(" ")
+			exception := handle.toException(err)
 			v.Status = G2T_STATUS_FAIL
+			v.Exception = exception
+			handle.notify(func(l Listener) { l.StepFailed(handle, v, exception) })
 			panic(exception)
 		}
 	}()
 
 	handle.Step = v
-	log.Infof("[STEP] %s", v.Text)
+	handle.notify(func(l Listener) { l.StepStarted(handle, v) })
+
+	// AfterStep hooks see the status of step, their failure fails the step if it passed
+	failure := v.invoke(handle)
+	if failure != nil {
+		v.Status = G2T_STATUS_FAIL
+		v.Exception = failure
+	} else {
+		v.Status = G2T_STATUS_PASS
+	}
+	if exception := handle.runner.runHooks(hookAfterStep, handle, handle.scenarioTags()); exception != nil && failure == nil {
+		failure = exception
+	}
+	if failure != nil {
+		panic(failure)
+	}
+	handle.notify(func(l Listener) { l.StepPassed(handle, v) })
+}
+
+// Run BeforeStep hooks and the action
+// @Params:
+//    handle: *Handle, it's created by Go2Test
+// @returns:
+//    (*Exception): Failure of hooks or action, nil if passed
+func (v *Step) invoke(handle *Handle) (exception *Exception) {
+	defer func() {
+		if err := recover(); err != nil {
+			exception = handle.toException(err)
+		}
+	}()
 
-	// rebuild the params with handle in the first
-	params := make([]reflect.Value, len(v.Params)+1)
-	params[0] = reflect.ValueOf(handle)
-	for id, p := range v.Params {
-		params[id+1]=p
+	if exception := handle.runner.runHooks(hookBeforeStep, handle, handle.scenarioTags()); exception != nil {
+		return exception
 	}
 
-	// Step will ignore the action's return
-	v.Action.Call(params)
-	v.Status = G2T_STATUS_PASS
+	// The context of step, it's cancelled when the step or scenario timed out
+	parent := handle.Context()
+	var ctx context.Context
+	var cancel context.CancelFunc
+	timeout := time.Duration(0)
+	if handle.runner != nil {
+		timeout = handle.runner.stepTimeout
+	}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	handle.ctx = ctx
+	defer func() {
+		cancel()
+		handle.ctx = parent
+	}()
+
+	// rebuild the params with handle in the first, context.Context in the second if action wants it
+	params := make([]reflect.Value, 0, len(v.Params)+2)
+	params = append(params, reflect.ValueOf(handle))
+	if paramOffset(v.Action.Type()) == 2 {
+		params = append(params, reflect.ValueOf(ctx))
+	}
+	params = append(params, v.Params...)
+
+	// Step fails if the last return is a non-nil error, other returns are ignored
+	var outs []reflect.Value
+	if _, ok := ctx.Deadline(); ok {
+		outs = v.callWithDeadline(handle, parent, ctx, params)
+	} else {
+		outs = v.Action.Call(params)
+	}
+	if len(outs) > 0 {
+		last := outs[len(outs)-1]
+		if last.Type() == errorType && !last.IsNil() {
+			return handle.WrapError(last.Interface().(error))
+		}
+	}
+	return nil
+}
+
+// Call the action in a goroutine, and give up waiting when ctx is done
+// The timed out action is waited as long as it was allowed to run, so after hooks do not share the handle with it
+// An action ignoring handle.Context() longer than that keeps running in background
+// @Params:
+//    handle: *Handle, it's created by Go2Test
+//    parent: The context of scenario
+//    ctx: The context of step
+//    params: Params pass to action
+// @returns:
+//    ([]reflect.Value): Returns of action
+func (v *Step) callWithDeadline(handle *Handle, parent context.Context, ctx context.Context, params []reflect.Value) []reflect.Value {
+	type callResult struct {
+		outs []reflect.Value
+		err  interface{}
+	}
+	deadline, _ := ctx.Deadline()
+	grace := time.Until(deadline)
+	done := make(chan *callResult, 1)
+	go func() {
+		result := new(callResult)
+		defer func() {
+			result.err = recover()
+			done <- result
+		}()
+		result.outs = v.Action.Call(params)
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			panic(result.err)
+		}
+		return result.outs
+	case <-ctx.Done():
+		elapsed := time.Since(v.StartTime).Round(time.Millisecond)
+		select {
+		case <-done:
+		case <-time.After(grace):
+			log.Warnf("[ TIMEOUT ] %s is still running", v.Text)
+		}
+		if parent.Err() != nil {
+			panic(handle.NewException("Scenario timed out: %s", parent.Err()))
+		}
+		panic(handle.NewException("Step timed out after %s: %s", elapsed, ctx.Err()))
+	}
 }
 
 // Skip the step, not run it
+// Undefined or ambiguous step keeps its status
 func (v *Step) Skip() {
+	if v.undefined() {
+		return
+	}
 	v.Status = G2T_STATUS_SKIP
 	log.Infof("[ SKIP ] %s", v.Text)
 }
 
+// Skip the step, and tell listeners
+// Undefined or ambiguous step keeps its status
+// @Params:
+//    handle: *Handle, it's created by Go2Test
+func (v *Step) skip(handle *Handle) {
+	if v.undefined() {
+		handle.notify(func(l Listener) { l.StepUndefined(handle, v) })
+		return
+	}
+	v.Status = G2T_STATUS_SKIP
+	handle.notify(func(l Listener) { l.StepSkipped(handle, v) })
+}
+
+// Whether no action or more than one actions matched the step
+func (v *Step) undefined() bool {
+	return v.Status == G2T_STATUS_UNDEFINED || v.Status == G2T_STATUS_AMBIGUOUS
+}
+
 
 // ----------------------------------------------------------------------------------
 // @name: Scenario
 // Groups of Steps
 // @params:
 //     Id: The order ID
+//     Keyword: Scenario|Scenario Outline
 //     Name: The name of Scenario
 //     Description: The Description of Scenario
-//     Steps: All Steps need to run(contains background)
-//     Status: Result WAIT|PASS|FAIL
+//     Line: Line number in *.feature (the example row for Scenario Outline)
+//     Tags: Tags of Scenario
+//     Timeout: Set by tag @timeout(30s), 0 if Go2Test's default is used
+//     Steps: All Steps need to run(contains background and before hooks)
+//     AfterSteps: Steps of after hooks, they always run after Steps, even if a step failed
+//     Status: Result WAIT|PASS|FAIL|SKIP|UNDEFINED|AMBIGUOUS, FAIL if an after hook failed
+//     Exception: The *Exception of the failed or undefined step
+//     AfterException: The *Exception of the first failed after hook step
+//     StartTime: When the scenario started
+//     Duration: How long the scenario took
 // ----------------------------------------------------------------------------------
 type Scenario struct {
 	Id              int
+	Keyword         string
 	Name            string
 	Description     string
+	Line            int
+	Tags            []string
+	Timeout         time.Duration
 	Steps           []*Step
+	AfterSteps      []*Step
 	Status          int
+	Exception       *Exception
+	AfterException  *Exception
+	StartTime       time.Time
+	Duration        time.Duration
 }
 
 // Run Scenario
@@ -181,34 +465,159 @@ type Scenario struct {
 //    handle: *Handle, it's created by Go2Test
 func (v *Scenario) Run(handle *Handle) {
 
+	v.StartTime = time.Now()
+
+	// Scenario with undefined or ambiguous steps does not run
+	for _, step := range v.AllSteps() {
+		if step.undefined() {
+			v.Skip(handle)
+			return
+		}
+	}
+
+	// The context of scenario, it's cancelled when the scenario timed out
+	timeout := v.Timeout
+	if timeout == 0 && handle.runner != nil {
+		timeout = handle.runner.scenarioTimeout
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		handle.ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		handle.ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	handle.Scenario = v
+	handle.notify(func(l Listener) { l.ScenarioStarted(handle, v) })
+
+	// BeforeFeature hooks failed
+	if handle.Feature != nil && handle.Feature.Exception != nil {
+		v.Status = G2T_STATUS_FAIL
+		v.Exception = handle.Feature.Exception
+		for _, step := range v.AllSteps() {
+			step.skip(handle)
+		}
+		v.Duration = time.Since(v.StartTime)
+		handle.notify(func(l Listener) { l.ScenarioFinished(handle, v) })
+		return
+	}
+
+	v.runSteps(handle)
+	// After hooks clean up even if the scenario timed out, only the step timeout applies to them
+	handle.ctx = context.Background()
+	v.runAfterSteps(handle)
+	handle.Step = nil
+	if exception := handle.runner.runHooks(hookAfterScenario, handle, v.Tags); exception != nil {
+		if v.AfterException == nil {
+			v.AfterException = exception
+		}
+		v.Status = G2T_STATUS_FAIL
+	}
+
+	v.Duration = time.Since(v.StartTime)
+	handle.notify(func(l Listener) { l.ScenarioFinished(handle, v) })
+}
+
+// Run BeforeScenario hooks and Steps, the remaining steps are skipped if a hook or step failed
+// @params:
+//    handle: *Handle, it's created by Go2Test
+func (v *Scenario) runSteps(handle *Handle) {
 	defer func() {
 		if err := recover(); err != nil {
 			v.Status = G2T_STATUS_FAIL
 			exception := err.(*Exception)
-			for _, step := range v.Steps[exception.Step.Id+1:] {
-				step.Skip()
+			v.Exception = exception
+			remaining := v.Steps
+			if exception.Step != nil {
+				remaining = v.Steps[exception.Step.Id+1:]
+			}
+			for _, step := range remaining {
+				step.skip(handle)
 			}
 		}
 	}()
 
-	handle.Scenario = v
-
-	log.Infof(" ")
-	log.Infof("----------------------------------------")
-	log.Infof("%s.%s", handle.Feature.Name, v.Name)
-	log.Infof("----------------------------------------")
-
+	if exception := handle.runner.runHooks(hookBeforeScenario, handle, v.Tags); exception != nil {
+		panic(exception)
+	}
 	for _, step := range v.Steps {
 		step.Run(handle)
 	}
 	v.Status = G2T_STATUS_PASS
 }
 
+// Run AfterSteps, every of them runs even if others failed
+// The scenario fails if any of them failed, the first failure is kept in AfterException
+// @params:
+//    handle: *Handle, it's created by Go2Test
+func (v *Scenario) runAfterSteps(handle *Handle) {
+	for _, step := range v.AfterSteps {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					if v.AfterException == nil {
+						v.AfterException = err.(*Exception)
+					}
+					v.Status = G2T_STATUS_FAIL
+				}
+			}()
+			step.Run(handle)
+		}()
+	}
+}
+
+// Steps and AfterSteps in the order they run
+func (v *Scenario) AllSteps() []*Step {
+	steps := make([]*Step, 0, len(v.Steps)+len(v.AfterSteps))
+	steps = append(steps, v.Steps...)
+	return append(steps, v.AfterSteps...)
+}
+
+// Skip the scenario, not run its steps
+// Scenario with undefined or ambiguous steps gets the status of the first of them
+// @params:
+//    handle: *Handle, it's created by Go2Test
+func (v *Scenario) Skip(handle *Handle) {
+	handle.Scenario = v
+	handle.notify(func(l Listener) { l.ScenarioStarted(handle, v) })
+	v.Status = G2T_STATUS_SKIP
+	for _, step := range v.AllSteps() {
+		step.skip(handle)
+		if step.undefined() && v.Status == G2T_STATUS_SKIP {
+			v.Status = step.Status
+			v.Exception = step.Exception
+		}
+	}
+	handle.notify(func(l Listener) { l.ScenarioFinished(handle, v) })
+}
+
+// ----------------------------------------------------------------------------------
+// @name: Hook
+// Hook scenario in *.feature, named "@before(priority) / regex" or "@after(priority) / tag:expression"
+// @values
+//    - Priority: Before hooks run from low to high, after hooks from high to low
+//    - Steps: Steps of hook
+//    - Regex: Matches the names of scenarios, nil if Tags is used
+//    - Tags: Matches the tags of scenarios, nil if Regex is used
+// ----------------------------------------------------------------------------------
 type Hook struct {
 	key       string
 	Priority  int
 	Steps     []*ghk.Step
 	Regex     *regexp.Regexp
+	Tags      TagExpression
+}
+
+// Whether the hook is for the scenario
+// @params:
+//    name: Name of scenario
+//    tags: Tags of scenario, include the inherited ones
+func (v *Hook) Match(name string, tags []string) bool {
+	if v.Tags != nil {
+		return v.Tags.Evaluate(tags)
+	}
+	return len(v.Regex.FindStringSubmatch(name)) != 0
 }
 
 func CreateHook(handle *Handle, gScenario *ghk.Scenario) (*Hook, *Exception) {
@@ -227,22 +636,30 @@ func CreateHook(handle *Handle, gScenario *ghk.Scenario) (*Hook, *Exception) {
 			head_checker, _ := regexp.Compile("(.+)\\((.+)\\)")
 			head_matched := head_checker.FindStringSubmatch(head)
 			if len(head_matched) != 3 {
-				return nil, handle.NewException(fmt.Sprintf("Invalid Hook title [%s]", sName))
+				return nil, handle.NewException("Invalid Hook title [%s]", sName)
 			}
 			key = strings.ToLower(strings.TrimSpace(head_matched[1]))
 			priority, err = strconv.Atoi(strings.TrimSpace(head_matched[2]))
 			if err != nil {
-				return nil, handle.NewException(fmt.Sprintf("Invalid Hook title [%s]: %s", sName, err.Error()))
+				return nil, handle.NewException("Invalid Hook title [%s]: %s", sName, err.Error())
 			}
 		} else {
 			key = strings.ToLower(head)
 			priority = 0
 		}
-		hook_checker, err := regexp.Compile(body)
-		if err != nil {
-			return nil, handle.NewException(fmt.Sprintf("Invalid Hook title [%s]: %s", sName, err.Error()))
+		if strings.HasPrefix(body, "tag:") {
+			expr, err := ParseTagExpression(body[len("tag:"):])
+			if err != nil {
+				return nil, handle.NewException("Invalid Hook title [%s]: %s", sName, err.Error())
+			}
+			hook.Tags = expr
+		} else {
+			hook_checker, err := regexp.Compile(body)
+			if err != nil {
+				return nil, handle.NewException("Invalid Hook title [%s]: %s", sName, err.Error())
+			}
+			hook.Regex = hook_checker
 		}
-		hook.Regex = hook_checker
 		hook.Steps = gScenario.Steps
 		hook.key = key
 		hook.Priority = priority
@@ -274,29 +691,133 @@ func (v HookList) Less(i, j int) bool {
 // @name: Feature
 // Scenario groups
 // @params:
+//     Path: The *.feature file
+//     Keyword: Feature
 //     Name: The feature's name
 //     Description: The feature's description
+//     Line: Line number in *.feature
+//     Tags: Tags of Feature
 //     Scenarios: All scenarios need to run(contains background)
 //     Status: Result WAIT|PASS|FAIL
+//     Exception: Failure of BeforeFeature or AfterFeature hooks
+//     StartTime: When the feature started
+//     Duration: How long the feature took
 // ----------------------------------------------------------------------------------
 type Feature struct {
+	Path         string
+	Keyword      string
 	Name         string
 	Scenarios    []*Scenario
 	Description  string
+	Line         int
+	Tags         []string
 	Status       int
+	Exception    *Exception
+	StartTime    time.Time
+	Duration     time.Duration
+	source       *gherkinSource
+}
+
+// Failure of feature hooks which no scenario reports, e.g. of AfterFeature hooks
+// Scenarios report the failure of BeforeFeature hooks as their own
+// @returns:
+//    (*Exception): nil if none
+func (v *Feature) hookException() *Exception {
+	if v.Exception == nil {
+		return nil
+	}
+	for _, scenario := range v.Scenarios {
+		if scenario.Exception == v.Exception {
+			return nil
+		}
+	}
+	return v.Exception
 }
 
 // Do the Feature
 // @params:
 //    handle: *Handle, it's created by Go2Test
 func (v *Feature) Run(handle *Handle) {
+	v.run(handle, 1, func(scenario *Scenario) {
+		scenario.Run(handle)
+	})
+}
+
+// Do the Feature, let the caller decide how to run each scenario
+// If concurrency > 1, scenarios run by a pool of workers, scenarios tagged @serial run one by one after them
+// @params:
+//    handle: *Handle, it's created by Go2Test
+//    concurrency: Number of workers
+//    each: Runs one scenario
+func (v *Feature) run(handle *Handle, concurrency int, each func(scenario *Scenario)) {
+	v.StartTime = time.Now()
+	defer func() {
+		v.Duration = time.Since(v.StartTime)
+		handle.notify(func(l Listener) { l.FeatureFinished(handle, v) })
+	}()
 	v.Status = G2T_STATUS_PASS
 	handle.Feature = v
+	handle.notify(func(l Listener) { l.FeatureStarted(handle, v) })
+
+	// Scenarios fail if BeforeFeature hooks failed, see Scenario.Run()
+	// BeforeFeature and AfterFeature hooks share a handle, so the Buffer keeps what they set up
+	if handle.runner != nil {
+		hookHandle := handle.runner.newHandle(v)
+		v.Exception = handle.runner.runHooks(hookBeforeFeature, hookHandle, v.Tags)
+		defer func() {
+			exception := handle.runner.runHooks(hookAfterFeature, hookHandle, v.Tags)
+			if exception != nil {
+				if v.Exception == nil {
+					v.Exception = exception
+				}
+				v.Status = G2T_STATUS_FAIL
+			}
+		}()
+	}
+
+	parallel := make([]*Scenario, 0)
+	serial := make([]*Scenario, 0)
 	for _, scenario := range v.Scenarios {
-		scenario.Run(handle)
-		if scenario.Status == G2T_STATUS_FAIL {
+		if concurrency > 1 && !matchTags(serialTag, scenario.Tags) {
+			parallel = append(parallel, scenario)
+		} else {
+			serial = append(serial, scenario)
+		}
+	}
+
+	jobs := make(chan *Scenario)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < concurrency && i < len(parallel); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for scenario := range jobs {
+				each(scenario)
+			}
+		}()
+	}
+	for _, scenario := range parallel {
+		jobs <- scenario
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, scenario := range serial {
+		each(scenario)
+	}
+
+	skipped := 0
+	for _, scenario := range v.Scenarios {
+		switch scenario.Status {
+		case G2T_STATUS_FAIL, G2T_STATUS_UNDEFINED, G2T_STATUS_AMBIGUOUS:
 			v.Status = G2T_STATUS_FAIL
 		}
+		if scenario.Status == G2T_STATUS_SKIP {
+			skipped++
+		}
+	}
+	if len(v.Scenarios) > 0 && skipped == len(v.Scenarios) {
+		v.Status = G2T_STATUS_SKIP
 	}
 }
 
@@ -308,114 +829,498 @@ func (v *Feature) Run(handle *Handle) {
 // ----------------------------------------------------------------------------------
 type Go2Test struct {
 	handle      *Handle
-	actions     map[*regexp.Regexp]reflect.Value
+	actions     []*stepAction
+	listeners   []Listener
+	concurrency int
+	stepTimeout     time.Duration
+	scenarioTimeout time.Duration
+	dryRun          bool
+	problems        []*Exception
+	snippetFile     string
+	mostSpecific    bool
+	parameterTypes  map[string]*parameterType
+	hooks           []*lifecycleHook
+	hookFiles       []string
+	shared          *hookLibrary
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: hookLibrary
+// Hooks and background shared by every feature of the run
+// They are read from *.hooks.feature and the files added by AddHookFile()
+// @values
+//    - before: Before hooks of all hook files, in the order of files
+//    - after: After hooks of all hook files, in the order of files
+//    - background: Background steps of all hook files, run before the feature's own background
+//    - sources: The hook files
+//    - paths: The hook file each step of hooks and background is written in
+// ----------------------------------------------------------------------------------
+type hookLibrary struct {
+	before      HookList
+	after       HookList
+	background  []*ghk.Step
+	sources     []*gherkinSource
+	paths       map[*ghk.Step]string
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: stepAction
+// Action added by AddAction(), kept in the order they are added
+// @values
+//    - regex: Regex to match step text
+//    - fn: The func
+//    - priority: Higher one wins if more than one actions matched
+//    - location: "file:line" of the func
+//    - params: Parameter type of each capture group, nil means converted by convertParam()
+//    - text: The regex or Cucumber Expression as added
+//    - expression: Whether text is Cucumber Expression
+// ----------------------------------------------------------------------------------
+type stepAction struct {
+	regex       *regexp.Regexp
+	fn          reflect.Value
+	priority    int
+	location    string
+	params      []*parameterType
+	text        string
+	expression  bool
+}
+
+// Convert the text of capture group into the type declared by action
+// @params:
+//    idx: Index of capture group
+//    text: Matched text
+//    typ: Type of action's param
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Conversion error
+func (v *stepAction) convert(idx int, text string, typ reflect.Type) (reflect.Value, error) {
+	if v.params == nil || v.params[idx] == nil {
+		return convertParam(text, typ)
+	}
+	return v.params[idx].convert(text, typ)
 }
 
 // Create new *Go2Test and init it
+// LogListener is registered by default
 // @returns:
 //    (*Go2Test): new *Go2Test
 func NewGo2Test() (*Go2Test) {
 	v := new(Go2Test)
-	v.actions = make(map[*regexp.Regexp]reflect.Value)
-	v.handle = new(Handle)
-	v.handle.Buffer = make(map[string]interface{})
+	v.actions = make([]*stepAction, 0)
+	v.parameterTypes = make(map[string]*parameterType)
+	for name, param := range builtinParameterTypes {
+		v.parameterTypes[name] = param
+	}
+	v.listeners = []Listener{new(LogListener)}
+	v.concurrency = 1
+	v.handle = v.newHandle(nil)
 	return v
 }
 
 
-// Add regex && action
+// Create new *Handle, every scenario has its own *Handle and Buffer
 // @params:
-//    reg: the regex to match step text
-//    action: func need to run if matched
+//    feature: The feature going to run
 // @returns:
-//    (error): Errors
-func (v *Go2Test) AddAction(reg string, action interface{}) *Exception {
-	key, err := regexp.Compile(reg)
-	if err != nil {
-		return v.handle.NewException(err.Error())
-	}
-	v.actions[key] = reflect.ValueOf(action)
-	return nil
+//    (*Handle): new *Handle
+func (v *Go2Test) newHandle(feature *Feature) *Handle {
+	handle := new(Handle)
+	handle.Buffer = make(map[string]interface{})
+	handle.Feature = feature
+	handle.runner = v
+	return handle
 }
 
 
-// Find matched action
+// Add hook file shared by every feature, like the *.hooks.feature found by Run()
+// It must only have hook scenarios and background, it does not run as a feature
 // @params:
-//    step: step's text
-// @returns:
-//    ([]string) matched words
-//    (*reflect.Value) action
-//    (*Exception) error
-func (v *Go2Test) findAction(step string) ([]string, *reflect.Value, *Exception) {
-	buf := make([]reflect.Value, 0)
-	matched := make([]string, 0)
-	for reg, action := range v.actions {
-		keywords := reg.FindStringSubmatch(step)
-		if len(keywords) != 0 {
-			matched = keywords
-			buf = append(buf, action)
-		}
-	}
+//    path: Path of file, glob pattern is supported
+func (v *Go2Test) AddHookFile(path string) {
+	v.hookFiles = append(v.hookFiles, path)
+}
 
-	switch len(buf) {
-	case 0:
-		return []string{}, nil, v.handle.NewException(fmt.Sprintf("Matched 0 function [%s]", step))
-	case 1:
-		return matched, &buf[0], nil
-	default:
-		return nil, nil, v.handle.NewException(fmt.Sprintf("Matched >1 functions [%s]", step))
-	}
+
+// Set the default timeout of every step
+// The step fails and remaining steps are skipped when it timed out
+// @params:
+//    timeout: 0 means no timeout
+func (v *Go2Test) SetStepTimeout(timeout time.Duration) {
+	v.stepTimeout = timeout
 }
 
 
-// read *.feature to create new *Feature
+// Set the default timeout of every scenario, tag @timeout(30s) overrides it
+// The running step fails and remaining steps are skipped when it timed out
 // @params:
-//    path: the path of *.feature
-// @returns
-//    (*Feature) new *Feature
-//    (error) Error
-func (v *Go2Test) createFeature(path string, tags []string) (*Feature, *Exception) {
+//    timeout: 0 means no timeout
+func (v *Go2Test) SetScenarioTimeout(timeout time.Duration) {
+	v.scenarioTimeout = timeout
+}
 
-	if tags == nil {
-		tags = make([]string, 0)
-	}
 
-	feature := new(Feature)
+// Set dry-run mode
+// Features are parsed and every step is resolved, but no action runs
+// Errors of all files are collected in Result.Errors instead of stopping at the first one
+// @params:
+//    dryRun: true to enable dry-run
+func (v *Go2Test) SetDryRun(dryRun bool) {
+	v.dryRun = dryRun
+}
 
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, v.handle.NewException(err.Error())
-	}
-	gFeature, err := ghk.ParseFeature(f)
-	f.Close()
-	if err != nil {
-		return nil, v.handle.NewException(err.Error())
-	}
 
-	if gFeature.Tags != nil && len(gFeature.Tags) > 0 && len(tags) > 0 {
-		bOK := false
-		for _, gTag := range gFeature.Tags {
-			for _, tag := range tags {
-				if tag == gTag.Name {
-					bOK = true
-					break
-				}
-			}
-		}
-		if !bOK {
-			return nil, nil
-		}
-	}
+// Write snippets of undefined steps into a file after each run, see Result.WriteSnippets()
+// @params:
+//    path: Path of file, empty to disable
+func (v *Go2Test) SetSnippetFile(path string) {
+	v.snippetFile = path
+}
 
 
-	// Description
-	feature.Description = gFeature.Description
-	feature.Name = gFeature.Name
+// Resolve ambiguous steps by the most specific action
+// If more than one actions with the same priority matched, the one matches the most text
+// outside its capture groups wins, e.g. "^Name Tom$" wins "^Name (.*)$"
+// @params:
+//    mostSpecific: true to enable it
+func (v *Go2Test) SetMostSpecificMatch(mostSpecific bool) {
+	v.mostSpecific = mostSpecific
+}
 
-	// Background
-	gBgSteps := []*ghk.Step{}
-	if gFeature.Background != nil {
-		gBgSteps = gFeature.Background.Steps
+
+// Set how many scenarios of a feature run at the same time
+// Scenarios tagged @serial never run with others
+// Listeners must be safe for concurrent use if concurrency > 1
+// @params:
+//    concurrency: Number of workers, 1 by default
+func (v *Go2Test) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	v.concurrency = concurrency
+}
+
+
+// Add listener to receive run events
+// @params:
+//    listener: Listener
+func (v *Go2Test) AddListener(listener Listener) {
+	v.listeners = append(v.listeners, listener)
+}
+
+
+// Replace all listeners, include the default LogListener
+// @params:
+//    listeners: Listeners, call with nothing to mute the run
+func (v *Go2Test) SetListeners(listeners ...Listener) {
+	v.listeners = listeners
+}
+
+
+// Add regex && action
+// The action must be func(*Handle, [table], captures...), every capture group of regex is a param
+// @params:
+//    reg: the regex to match step text
+//    action: func need to run if matched
+// @returns:
+//    (error): Errors
+func (v *Go2Test) AddAction(reg string, action interface{}) *Exception {
+	return v.AddActionWithPriority(reg, 0, action)
+}
+
+
+// Add regex && action with priority
+// If more than one actions matched a step, the one with the highest priority wins
+// @params:
+//    reg: the regex to match step text
+//    priority: 0 by AddAction()
+//    action: func need to run if matched
+// @returns:
+//    (error): Errors
+func (v *Go2Test) AddActionWithPriority(reg string, priority int, action interface{}) *Exception {
+	key, err := regexp.Compile(reg)
+	if err != nil {
+		return v.handle.NewException("%s", err.Error())
+	}
+	return v.addAction(reg, key, nil, false, priority, action)
+}
+
+
+// Add Cucumber Expression && action
+// The action must be func(*Handle, [table], params...), every parameter of expression is a param
+// Built-in parameters: {int}, {float}, {word}, {string} and {} (anything)
+// @params:
+//    expr: the expression to match step text, e.g. "{word}'s name: {string}"
+//          parameter types added by AddParameterType() work too
+//    action: func need to run if matched
+// @returns:
+//    (error): Errors
+func (v *Go2Test) AddStep(expr string, action interface{}) *Exception {
+	key, params, err := compileExpression(expr, v.parameterTypes)
+	if err != nil {
+		return v.handle.NewException("%s", err.Error())
+	}
+	return v.addAction(expr, key, params, true, 0, action)
+}
+
+
+// Check action and add it
+// @params:
+//    text: regex or expression, for error message
+//    key: the compiled regex
+//    params: parameter types of expression, nil for regex
+//    expression: whether text is Cucumber Expression
+//    priority: see AddActionWithPriority()
+//    action: func need to run if matched
+// @returns:
+//    (error): Errors
+func (v *Go2Test) addAction(text string, key *regexp.Regexp, params []*parameterType, expression bool,
+	priority int, action interface{}) *Exception {
+	value := reflect.ValueOf(action)
+	if params == nil && value.Kind() == reflect.Func {
+		params = v.regexParams(value.Type(), key.NumSubexp())
+	}
+	if err := checkAction(value, key.NumSubexp(), params); err != nil {
+		return v.handle.NewException("Invalid action of [%s]: %s", text, err.Error())
+	}
+	v.actions = append(v.actions, &stepAction{
+		regex:    key,
+		fn:       value,
+		priority: priority,
+		location: actionLocation(value),
+		params:   params,
+		text:     text,
+		expression: expression,
+	})
+	return nil
+}
+
+
+// Add parameter type, so steps can be bound to domain values, e.g. {user} => *User
+// It's used by {name} in AddStep(), and by params of type T in AddAction()
+// Please add it before the actions using it
+// @params:
+//    name: Name in expression, e.g. "user"
+//    regex: Regex of the text, e.g. "[A-Z][a-z]+", use (?:...) instead of capture groups
+//    transformer: func(string) T or func(string) (T, error)
+// @returns:
+//    (error): Errors
+func (v *Go2Test) AddParameterType(name string, regex string, transformer interface{}) *Exception {
+	if _, ok := v.parameterTypes[name]; ok {
+		return v.handle.NewException("Parameter type {%s} already exists", name)
+	}
+	param, err := newParameterType(name, regex, transformer)
+	if err != nil {
+		return v.handle.NewException("Invalid parameter type {%s}: %s", name, err.Error())
+	}
+	v.parameterTypes[name] = param
+	return nil
+}
+
+
+// Parameter types of regex captures, by the types declared by action
+// @params:
+//    actionType: Type of action
+//    captures: Number of capture groups in regex
+// @returns:
+//    ([]*parameterType) nil if no capture uses added parameter types
+func (v *Go2Test) regexParams(actionType reflect.Type, captures int) []*parameterType {
+	// Too few params, checkAction() reports it
+	first := actionType.NumIn() - captures
+	if first < 1 {
+		return nil
+	}
+	var params []*parameterType
+	for idx := 0; idx < captures; idx++ {
+		param := v.parameterTypeOf(actionType.In(first + idx))
+		if param == nil {
+			continue
+		}
+		if params == nil {
+			params = make([]*parameterType, captures)
+		}
+		params[idx] = param
+	}
+	return params
+}
+
+
+// Parameter type added by AddParameterType() which gives the type
+// If more than one types give it, the first one by name is used
+// Built-in types, e.g. string, float64 and time.Duration, keep the built-in conversion,
+// use {name} in AddStep() for them
+// @params:
+//    typ: Type of value
+// @returns:
+//    (*parameterType) nil if not found
+func (v *Go2Test) parameterTypeOf(typ reflect.Type) *parameterType {
+	if isBuiltinType(typ) {
+		return nil
+	}
+	var ret *parameterType
+	for _, param := range v.parameterTypes {
+		if !param.transformer.IsValid() || param.typ != typ {
+			continue
+		}
+		if ret == nil || param.name < ret.name {
+			ret = param
+		}
+	}
+	return ret
+}
+
+
+// Find matched action
+// If more than one actions matched, keep the ones with the highest priority,
+// then the most specific ones if SetMostSpecificMatch() is enabled
+// @params:
+//    step: step's text
+// @returns:
+//    ([]string) matched words
+//    (*stepAction) action
+//    (int) G2T_STATUS_UNDEFINED|G2T_STATUS_AMBIGUOUS if failed
+//    (*Exception) error, every matched regex and its action are listed if ambiguous
+func (v *Go2Test) findAction(step string) ([]string, *stepAction, int, *Exception) {
+	buf := make([]*stepAction, 0)
+	matched := make([][]string, 0)
+	for _, action := range v.actions {
+		keywords := action.regex.FindStringSubmatch(step)
+		if len(keywords) != 0 {
+			matched = append(matched, keywords)
+			buf = append(buf, action)
+		}
+	}
+	if len(buf) > 1 {
+		buf, matched = bestActions(buf, matched, func(keywords []string, action *stepAction) int {
+			return action.priority
+		})
+	}
+	if len(buf) > 1 && v.mostSpecific {
+		buf, matched = bestActions(buf, matched, func(keywords []string, action *stepAction) int {
+			return literalLength(action.regex, step)
+		})
+	}
+
+	switch len(buf) {
+	case 0:
+		return []string{}, nil, G2T_STATUS_UNDEFINED, v.handle.NewException("Matched 0 function [%s]", step)
+	case 1:
+		return matched[0], buf[0], G2T_STATUS_WAIT, nil
+	default:
+		lines := []string{fmt.Sprintf("Matched %d functions [%s]", len(buf), step)}
+		for _, action := range buf {
+			lines = append(lines, fmt.Sprintf("    `%s` at %s", action.regex.String(), action.location))
+		}
+		return nil, nil, G2T_STATUS_AMBIGUOUS, v.handle.NewException("%s", strings.Join(lines, "\n"))
+	}
+}
+
+
+// Count of step's characters not captured by the regex
+// Groups nested in another group are already counted by the outer one
+// @params:
+//    regex: Regex matched the step
+//    step: Text of step
+// @returns:
+//    (int) Length of step without captured words
+func literalLength(regex *regexp.Regexp, step string) int {
+	index := regex.FindStringSubmatchIndex(step)
+	literal := index[1] - index[0]
+	end := -1
+	for i := 2; i+1 < len(index); i += 2 {
+		if index[i] < 0 || index[i] < end {
+			continue
+		}
+		literal -= index[i+1] - index[i]
+		end = index[i+1]
+	}
+	return literal
+}
+
+
+// Keep the actions with the highest score
+// @params:
+//    actions: Matched actions
+//    matched: Matched words of each action
+//    score: Score of action
+// @returns:
+//    ([]*stepAction) Actions with the highest score
+//    ([][]string) Their matched words
+func bestActions(actions []*stepAction, matched [][]string,
+	score func([]string, *stepAction) int) ([]*stepAction, [][]string) {
+	best := score(matched[0], actions[0])
+	for idx, action := range actions {
+		if s := score(matched[idx], action); s > best {
+			best = s
+		}
+	}
+	retActions := make([]*stepAction, 0, len(actions))
+	retMatched := make([][]string, 0, len(actions))
+	for idx, action := range actions {
+		if score(matched[idx], action) == best {
+			retActions = append(retActions, action)
+			retMatched = append(retMatched, matched[idx])
+		}
+	}
+	return retActions, retMatched
+}
+
+
+// Source location of action
+// @params:
+//    action: func added by AddAction()
+// @returns:
+//    (string): "file:line" of the func, empty if unknown
+func actionLocation(action reflect.Value) string {
+	if action.Kind() != reflect.Func {
+		return ""
+	}
+	fn := runtime.FuncForPC(action.Pointer())
+	if fn == nil {
+		return ""
+	}
+	file, line := fn.FileLine(fn.Entry())
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+
+// read *.feature to create new *Feature
+// @params:
+//    path: the path of *.feature
+//    filter: tag expression, nil if no filter
+// @returns
+//    (*Feature) new *Feature
+//    (error) Error
+func (v *Go2Test) createFeature(path string, filter TagExpression) (*Feature, *Exception) {
+
+	feature := new(Feature)
+
+	source, exp := v.parseFeature(path)
+	if exp != nil {
+		return nil, exp
+	}
+	gFeature := source.document
+
+	// Exceptions of this feature know the file
+	v.handle.Feature = feature
+	feature.source = source
+
+	// Description
+	feature.Path = path
+	feature.Keyword = gFeature.Keyword
+	feature.Description = gFeature.Description
+	feature.Name = gFeature.Name
+	feature.Line = lineOf(gFeature.Location)
+	feature.Tags = tagNames(gFeature.Tags)
+
+	// Background, the shared one runs first
+	gBgSteps := []*ghk.Step{}
+	if v.shared != nil {
+		gBgSteps = append(gBgSteps, v.shared.background...)
+	}
+	if gFeature.Background != nil {
+		gBgSteps = append(gBgSteps, gFeature.Background.Steps...)
 	}
 
 	// Find Hooks
@@ -423,6 +1328,99 @@ func (v *Go2Test) createFeature(path string, tags []string) (*Feature, *Exceptio
 	// Example:
 	// Scenario: @before/^(/*)$  match all scenarios
 	// Scenario: @before/^(.*)stg(.*)$  match all scenarios contains "stg" in its name
+	hooklib_be, hooklib_af, normal_sce, exp := v.collectHooks(gFeature)
+	if exp != nil {
+		return nil, exp
+	}
+
+	// Shared hooks wrap the feature's hooks of the same priority:
+	// their before hooks run first and their after hooks run last
+	if v.shared != nil {
+		hooklib_be = append(append(HookList{}, v.shared.before...), hooklib_be...)
+		hooklib_af = append(hooklib_af, v.shared.after...)
+	}
+
+	// sort hooklib, after hooks run in reverse order of priority
+	sort.Stable(hooklib_be)
+	sort.Stable(sort.Reverse(hooklib_af))
+
+	// Scenario
+	feature.Scenarios = make([]*Scenario, 0)
+	for _, s := range normal_sce {
+		gScenario, ok := s.(*ghk.Scenario)
+
+		if ok {
+			scenario, err := v.createScenario(gScenario, gBgSteps, hooklib_be, hooklib_af, feature.Tags, filter)
+			if err!= nil {
+				return nil, err
+			}
+			if scenario != nil {
+				scenario.Id = len(feature.Scenarios)
+				feature.Scenarios = append(feature.Scenarios, scenario)
+			}
+		} else {
+			scenarios, err := v.createScenarioArray(s.(*ghk.ScenarioOutline), gBgSteps, hooklib_be, hooklib_af, feature.Tags, filter)
+			if err!= nil {
+				return nil, err
+			}
+			for _, scenario := range scenarios {
+				scenario.Id = len(feature.Scenarios)
+				feature.Scenarios = append(feature.Scenarios, scenario)
+			}
+		}
+
+	}
+
+	// No scenario passed the filter
+	if filter != nil && len(feature.Scenarios) == 0 {
+		return nil, nil
+	}
+	return feature,nil
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: gherkinSource
+// *.feature and its parsed document, kept for Cucumber Messages
+// @values
+//    - uri: Path of file
+//    - data: Text of file
+//    - document: Parsed feature
+// ----------------------------------------------------------------------------------
+type gherkinSource struct {
+	uri         string
+	data        string
+	document    *ghk.Feature
+}
+
+// Parse *.feature
+// @params:
+//    path: the path of *.feature
+// @returns:
+//    (*gherkinSource): The file and parsed feature
+//    (*Exception): Read or syntax error
+func (v *Go2Test) parseFeature(path string) (*gherkinSource, *Exception) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, v.handle.NewException("%s", err.Error())
+	}
+	gFeature, err := ghk.ParseFeature(bytes.NewReader(data))
+	if err != nil {
+		return nil, v.handle.NewException("%s", err.Error())
+	}
+	return &gherkinSource{uri: path, data: string(data), document: gFeature}, nil
+}
+
+
+// Split scenarios of feature into hooks and normal scenarios
+// @params:
+//    gFeature: Parsed feature
+// @returns:
+//    (HookList): Before hooks, in the order of file
+//    (HookList): After hooks, in the order of file
+//    ([]interface{}): *ghk.Scenario and *ghk.ScenarioOutline which are not hooks
+//    (*Exception): Invalid hook
+func (v *Go2Test) collectHooks(gFeature *ghk.Feature) (HookList, HookList, []interface{}, *Exception) {
 	hooklib_be := make(HookList, 0)
 	hooklib_af := make(HookList, 0)
 	normal_sce := make([]interface{}, 0)
@@ -437,7 +1435,7 @@ func (v *Go2Test) createFeature(path string, tags []string) (*Feature, *Exceptio
 
 		hook,exp := CreateHook(v.handle, gScenario)
 		if exp != nil {
-			return nil, exp
+			return nil, nil, nil, exp
 		}
 
 		if hook == nil {
@@ -451,91 +1449,106 @@ func (v *Go2Test) createFeature(path string, tags []string) (*Feature, *Exceptio
 		case "after":
 			hooklib_af = append(hooklib_af, hook)
 		default:
-			return nil, v.handle.NewException("Find unsupported hook tag: [%s]", hook.key)
+			return nil, nil, nil, v.handle.NewException("Find unsupported hook tag: [%s]", hook.key)
 		}
 	}
+	return hooklib_be, hooklib_af, normal_sce, nil
+}
 
-	// sort hooklib
-	sort.Sort(hooklib_be)
-	sort.Sort(hooklib_af)
 
-	// Scenario
-	feature.Scenarios = make([]*Scenario, 0)
-	for _, s := range normal_sce {
-		gScenario, ok := s.(*ghk.Scenario)
+// Read hook file and add its hooks and background to the shared library
+// @params:
+//    path: the path of hook file
+// @returns:
+//    (*Exception): Parse error, invalid hook, or the file has normal scenarios
+func (v *Go2Test) loadHookFile(path string) *Exception {
+	source, exp := v.parseFeature(path)
+	if exp != nil {
+		return exp
+	}
+	gFeature := source.document
+	v.handle.Feature = &Feature{Path: path, Name: gFeature.Name, Line: lineOf(gFeature.Location)}
 
-		var hook_b []*ghk.Step
-		var hook_a []*ghk.Step
-//		hook_a := make(map[int][]*ghk.Step)
+	hooklib_be, hooklib_af, normal_sce, exp := v.collectHooks(gFeature)
+	if exp != nil {
+		return exp
+	}
+	if len(normal_sce) > 0 {
+		return v.handle.NewException("Hook file must only have hook scenarios, found [%s]",
+			scenarioName(normal_sce[0]))
+	}
 
-		if ok {
-			// Search matched before hook
-			hook_b = GetHookSteps(hooklib_be, strings.TrimSpace(gScenario.Name))
-			hook_a = GetHookSteps(hooklib_af, strings.TrimSpace(gScenario.Name))
-			scenario, err := v.createScenario(gScenario, gBgSteps, hook_b, hook_a, tags)
-			if err!= nil {
-				return nil, err
-			}
-			if scenario != nil {
-				scenario.Id = len(feature.Scenarios)
-				feature.Scenarios = append(feature.Scenarios, scenario)
-			}
-		} else {
-			hook_b = GetHookSteps(hooklib_be, strings.TrimSpace(s.(*ghk.ScenarioOutline).Name))
-			hook_a = GetHookSteps(hooklib_af, strings.TrimSpace(s.(*ghk.ScenarioOutline).Name))
-			scenarios, err := v.createScenarioArray(s.(*ghk.ScenarioOutline), gBgSteps, hook_b, hook_a, tags)
-			if err!= nil {
-				return nil, err
-			}
-			for _, scenario := range scenarios {
-				scenario.Id = len(feature.Scenarios)
-				feature.Scenarios = append(feature.Scenarios, scenario)
-			}
+	v.shared.before = append(v.shared.before, hooklib_be...)
+	v.shared.after = append(v.shared.after, hooklib_af...)
+	for _, hook := range append(hooklib_be, hooklib_af...) {
+		for _, gStep := range hook.Steps {
+			v.shared.paths[gStep] = path
+		}
+	}
+	if gFeature.Background != nil {
+		v.shared.background = append(v.shared.background, gFeature.Background.Steps...)
+		for _, gStep := range gFeature.Background.Steps {
+			v.shared.paths[gStep] = path
 		}
+	}
+	v.shared.sources = append(v.shared.sources, source)
+	return nil
+}
+
 
+// Name of *ghk.Scenario or *ghk.ScenarioOutline
+func scenarioName(s interface{}) string {
+	switch gScenario := s.(type) {
+	case *ghk.Scenario:
+		return strings.TrimSpace(gScenario.Name)
+	case *ghk.ScenarioOutline:
+		return strings.TrimSpace(gScenario.Name)
 	}
-	return feature,nil
+	return ""
 }
 
 
 // Create new *Scenario
 // @params:
 //    gScenario: ghk.Scenario
+//    hooklib_be, hooklib_af: Hooks of feature, the ones matched the scenario's name or tags are used
+//    featureTags: Tags inherited from Feature
+//    filter: tag expression, nil if no filter
 // @returns:
 //    (*Scenario) new *Scenario
 //    (*Exception) *Exception
 func (v *Go2Test) createScenario(gScenario *ghk.Scenario, bgSteps []*ghk.Step,
-				hook_b []*ghk.Step, hook_a []*ghk.Step, tags[] string ) (*Scenario, *Exception) {
+				hooklib_be HookList, hooklib_af HookList, featureTags []string, filter TagExpression) (*Scenario, *Exception) {
 
 	scenario := new(Scenario)
 
-	if len(tags) > 0 {
-		bOK := false
-		if gScenario.Tags != nil && len(gScenario.Tags) > 0 {
-			for _, gTag := range gScenario.Tags {
-				for _, tag := range tags {
-					if tag == gTag.Name {
-						bOK = true
-						break
-					}
-				}
-			}
-		}
-		if !bOK {
-			return nil, nil
-		}
+	// Scenario inherits the tags of Feature
+	scenario.Tags = mergeTags(featureTags, tagNames(gScenario.Tags))
+	if !matchTags(filter, scenario.Tags) {
+		return nil, nil
 	}
+	timeout, err := parseTimeoutTag(scenario.Tags)
+	if err != nil {
+		return nil, v.handle.NewException("Scenario [%s]: %s", gScenario.Name, err.Error())
+	}
+	scenario.Timeout = timeout
+
+	// Search matched hooks
+	hook_b := hooklib_be.Steps(strings.TrimSpace(gScenario.Name), scenario.Tags)
+	hook_a := hooklib_af.Steps(strings.TrimSpace(gScenario.Name), scenario.Tags)
 
 	// Description
+	scenario.Keyword = gScenario.Keyword
 	scenario.Name = gScenario.Name
 	scenario.Description = gScenario.Description
+	scenario.Line = lineOf(gScenario.Location)
 
 	// Step
 	scenario.Steps = make([]*Step, 0)
 
 	for i:=0; i<len(bgSteps); i++ {
 		step, err := v.createStep(bgSteps[i], map[string]string{})
-		if err != nil {
+		if err = v.checkStep(step, err); err != nil {
 			return nil, err
 		}
 		step.Id = len(scenario.Steps)
@@ -544,7 +1557,7 @@ func (v *Go2Test) createScenario(gScenario *ghk.Scenario, bgSteps []*ghk.Step,
 
 	for i:=0; i<len(hook_b); i++  {
 		step, err := v.createStep(hook_b[i], map[string]string{})
-		if err != nil {
+		if err = v.checkStep(step, err); err != nil {
 			return nil, err
 		}
 		step.Id = len(scenario.Steps)
@@ -553,64 +1566,84 @@ func (v *Go2Test) createScenario(gScenario *ghk.Scenario, bgSteps []*ghk.Step,
 
 	for i:=0; i<len(gScenario.Steps); i++ {
 		step, err := v.createStep(gScenario.Steps[i], map[string]string{})
-		if err != nil {
+		if err = v.checkStep(step, err); err != nil {
 			return nil, err
 		}
 		step.Id = len(scenario.Steps)
 		scenario.Steps = append(scenario.Steps, step)
 	}
 
-	for i:=len(hook_a)-1; i>=0; i-- {
-		step, err := v.createStep(hook_a[i], map[string]string{})
-		if err != nil {
-			return nil, err
-		}
-		step.Id = len(scenario.Steps)
-		scenario.Steps = append(scenario.Steps, step)
+	afterSteps, exp := v.createAfterSteps(hook_a, len(scenario.Steps))
+	if exp != nil {
+		return nil, exp
 	}
+	scenario.AfterSteps = afterSteps
 
 	return scenario, nil
 }
 
 
+// ----------------------------------------------------------------------------------
+// Create Steps of after hooks
+// @param
+//    hook_a: ([]*ghk.Step) Steps of after hooks, in the order they run
+//    firstId: (int) Id of the first step, after the scenario's steps
+// @return
+//    ([]*Step) The steps
+//    (*Exception) if anything failed
+// ----------------------------------------------------------------------------------
+func (v *Go2Test) createAfterSteps(hook_a []*ghk.Step, firstId int) ([]*Step, *Exception) {
+	steps := make([]*Step, 0, len(hook_a))
+	for _, gStep := range hook_a {
+		step, err := v.createStep(gStep, map[string]string{})
+		if err = v.checkStep(step, err); err != nil {
+			return nil, err
+		}
+		step.Id = firstId + len(steps)
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+
 // ----------------------------------------------------------------------------------
 // Create *Scenario form *ghk.ScenarioOutline
+// Every row of Examples is a *Scenario, it inherits the tags of Feature, Scenario Outline and Examples
 // @param
 //    gScenario: (*ghk.ScenarioOutline) The instance of *ghk.ScenarioOutline
+//    hooklib_be, hooklib_af: (HookList) Hooks of feature, the ones matched the outline's name or tags are used
+//    featureTags: ([]string) Tags inherited from Feature
+//    filter: (TagExpression) tag expression, nil if no filter
 // @return
 //    (*Scenario) The Scenario{} instance
 //    (error) if anything failed
 // ----------------------------------------------------------------------------------
 func (v *Go2Test) createScenarioArray( gScenario *ghk.ScenarioOutline,
-                  bgSteps []*ghk.Step, hook_b []*ghk.Step, hook_a []*ghk.Step, tags[] string) ([]*Scenario, *Exception) {
+                  bgSteps []*ghk.Step, hooklib_be HookList, hooklib_af HookList,
+                  featureTags []string, filter TagExpression) ([]*Scenario, *Exception) {
 	scenarios := make([]*Scenario, 0)
+	outlineTags := mergeTags(featureTags, tagNames(gScenario.Tags))
 
-	// Check Tags
-	// Check Tags
-	// For scenario, if gTags is empty and tags is not empty, is not allowed
-	if len(tags) > 0 {
-		bOK := false
-		if gScenario.Tags != nil && len(gScenario.Tags) > 0 {
-			for _, gTag := range gScenario.Tags {
-				for _, tag := range tags {
-					if tag == gTag.Name {
-						bOK = true
-						break
-					}
-				}
-			}
+	for _, gExample := range gScenario.Examples {
+		// Check Tags, Examples can be filtered by its own tags
+		exampleTags := mergeTags(outlineTags, tagNames(gExample.Tags))
+		if !matchTags(filter, exampleTags) {
+			continue
 		}
-		if !bOK {
-			return nil, nil
+		timeout, err := parseTimeoutTag(exampleTags)
+		if err != nil {
+			return nil, v.handle.NewException("Scenario [%s]: %s", gScenario.Name, err.Error())
 		}
-	}
-
-
-	for _, gExample := range gScenario.Examples {
+		hook_b := hooklib_be.Steps(strings.TrimSpace(gScenario.Name), exampleTags)
+		hook_a := hooklib_af.Steps(strings.TrimSpace(gScenario.Name), exampleTags)
 		for id, body := range gExample.TableBody {
 			scenario := new(Scenario)
-			scenario.Name = gScenario.Name + " | " + gExample.Name + " | " + string(id)
+			scenario.Keyword = gScenario.Keyword
+			scenario.Name = gScenario.Name + " | " + gExample.Name + " | " + strconv.Itoa(id)
 			scenario.Description = gScenario.Description
+			scenario.Line = lineOf(body.Location)
+			scenario.Tags = exampleTags
+			scenario.Timeout = timeout
 			data := map[string]string{}
 			for i, cell := range body.Cells {
 				data[gExample.TableHeader.Cells[i].Value] = cell.Value
@@ -618,15 +1651,16 @@ func (v *Go2Test) createScenarioArray( gScenario *ghk.ScenarioOutline,
 			scenario.Steps = make([]*Step, 0)
 			for _, gStep := range bgSteps {
 				step, err := v.createStep(gStep, map[string]string{})
-				if err != nil {
+				if err = v.checkStep(step, err); err != nil {
 					return nil, err
 				}
+				step.Id = len(scenario.Steps)
 				scenario.Steps = append(scenario.Steps, step)
 			}
 
 			for _, gStep := range hook_b {
 				step, err := v.createStep(gStep, map[string]string{})
-				if err != nil {
+				if err = v.checkStep(step, err); err != nil {
 					return nil, err
 				}
 				step.Id = len(scenario.Steps)
@@ -635,20 +1669,18 @@ func (v *Go2Test) createScenarioArray( gScenario *ghk.ScenarioOutline,
 
 			for _, gStep := range gScenario.Steps {
 				step, err := v.createStep(gStep, data)
-				if err != nil {
+				if err = v.checkStep(step, err); err != nil {
 					return nil, err
 				}
+				step.Id = len(scenario.Steps)
 				scenario.Steps = append(scenario.Steps, step)
 			}
 
-			for _, gStep := range hook_a {
-				step, err := v.createStep(gStep, map[string]string{})
-				if err != nil {
-					return nil, err
-				}
-				step.Id = len(scenario.Steps)
-				scenario.Steps = append(scenario.Steps, step)
+			afterSteps, exp := v.createAfterSteps(hook_a, len(scenario.Steps))
+			if exp != nil {
+				return nil, exp
 			}
+			scenario.AfterSteps = afterSteps
 
 			scenarios = append(scenarios, scenario)
 		}
@@ -657,18 +1689,55 @@ func (v *Go2Test) createScenarioArray( gScenario *ghk.ScenarioOutline,
 }
 
 
+// ----------------------------------------------------------------------------------
+// Check the error of createStep()
+// Undefined and ambiguous steps are collected and kept, the scenario will not run but others will
+// In dry-run mode, all errors are collected and the steps are kept, so other steps can be checked
+// @param
+//    step: (*Step) Created step
+//    err: (*Exception) Error of createStep()
+// @return
+//    (*Exception) The error, nil if no error or it's collected
+// ----------------------------------------------------------------------------------
+func (v *Go2Test) checkStep(step *Step, err *Exception) *Exception {
+	if err == nil {
+		return nil
+	}
+	err.Step = step
+	step.Exception = err
+	if !v.dryRun && !step.undefined() {
+		return err
+	}
+	v.problems = append(v.problems, err)
+	return nil
+}
+
+
 // ----------------------------------------------------------------------------------
 // Create Step form *ghk.Step
 // @param
 //    gStep: (*ghk.Step) Instance of *ghk.Step
 //    example: (map[string]string) Line of Example
 // @return
-//    (*Step) The Step{} instance
+//    (*Step) The Step{} instance, it's returned with the error so the error can be collected
 //    (error) if anything failed
 // ----------------------------------------------------------------------------------
 func (v *Go2Test) createStep(gStep *ghk.Step, example map[string]string, ) (*Step, *Exception) {
 	step := new(Step)
+	step.Keyword = gStep.Keyword
 	step.Text = strings.TrimSpace(gStep.Text)
+	step.Line = lineOf(gStep.Location)
+	step.gherkin = gStep
+
+	// Steps of hook files are reported where they are written, not in the running feature
+	if v.handle.Feature != nil {
+		step.Path = v.handle.Feature.Path
+	}
+	if v.shared != nil {
+		if path, ok := v.shared.paths[gStep]; ok {
+			step.Path = path
+		}
+	}
 	step.Params = make([]reflect.Value, 0)
 
 	// update step text with example data
@@ -676,92 +1745,325 @@ func (v *Go2Test) createStep(gStep *ghk.Step, example map[string]string, ) (*Ste
 		step.Text = strings.Replace(step.Text, "<" + key + ">", val, -1)
 	}
 
-	// If with a special param
+	// If with a special param, it's converted after the action is found, by the type it declares
 	data, ok := gStep.Argument.(*ghk.DataTable)
 	if ok {
-		if len(data.Rows[0].Cells) > 1 {
-			// It's a map
-			param := make([]map[string]string, 0)
-			for _, row := range data.Rows[1:] {
-				r := make(map[string]string)
-				for idx, cell := range row.Cells {
-					r[data.Rows[0].Cells[idx].Value] = cell.Value
-				}
-				param = append(param, r)
-			}
-			step.Params = append(step.Params, reflect.ValueOf(param))
-		} else {
-			// It's a slice
-			param := make([]string, 0)
-			for _, row := range data.Rows {
-				param = append(param, row.Cells[0].Value)
+		step.Rows = make([][]string, 0, len(data.Rows))
+		for _, row := range data.Rows {
+			cells := make([]string, 0, len(row.Cells))
+			for _, cell := range row.Cells {
+				cells = append(cells, cell.Value)
 			}
-			step.Params = append(step.Params, reflect.ValueOf(param))
+			step.Rows = append(step.Rows, cells)
+		}
+	}
+	if doc, ok := gStep.Argument.(*ghk.DocString); ok {
+		step.DocString = &DocString{ContentType: doc.ContentType, Content: doc.Content}
+		for key, val := range example {
+			step.DocString.Content = strings.Replace(step.DocString.Content, "<" + key + ">", val, -1)
 		}
 	}
 
 	// Find Keywords, Action
-	keywords, action, err := v.findAction(step.Text)
+	keywords, action, status, err := v.findAction(step.Text)
 	if err != nil {
-		return nil, err
+		step.Status = status
+		return step, err
+	}
+	step.Action = action.fn
+	step.action = action
+
+	// Check the special param
+	// The first param of action is *Handle, context.Context may follow it
+	actionType := step.Action.Type()
+	offset := paramOffset(actionType)
+	if step.Rows != nil {
+		if offset >= actionType.NumIn() || !isTableType(actionType.In(offset)) {
+			return step, v.handle.NewException("Step [%s]: action does not accept table",
+				step.Text)
+		}
+		param, err := v.convertTable(step.Rows, actionType.In(offset))
+		if err != nil {
+			return step, v.handle.NewException("Step [%s]: cannot convert table to %s: %s",
+				step.Text, actionType.In(offset), err.Error())
+		}
+		step.Params = append(step.Params, param)
+	}
+	if step.DocString != nil {
+		if offset >= actionType.NumIn() || !isDocStringType(actionType.In(offset)) {
+			return step, v.handle.NewException("Step [%s]: action does not accept DocString",
+				step.Text)
+		}
+		param, err := convertDocString(step.DocString, actionType.In(offset))
+		if err != nil {
+			return step, v.handle.NewException("Step [%s]: cannot convert DocString to %s: %s",
+				step.Text, actionType.In(offset), err.Error())
+		}
+		step.Params = append(step.Params, param)
+	}
+	for id, param := range step.Params {
+		if id+offset >= actionType.NumIn() || !param.Type().AssignableTo(actionType.In(id+offset)) {
+			return step, v.handle.NewException("Step [%s]: action does not accept %s",
+				step.Text, param.Type())
+		}
 	}
-	step.Action = *action
 
-	// If with regex params
+	// If with regex params, convert them into the types declared by action
 	if len(keywords) > 1 {
-		for _, keyword := range keywords[1:] {
-			step.Params = append(step.Params, reflect.ValueOf(keyword))
+		for idx, keyword := range keywords[1:] {
+			index := len(step.Params) + offset
+			if index >= actionType.NumIn() {
+				return step, v.handle.NewException("Step [%s]: action accepts %d params, but got %d",
+					step.Text, actionType.NumIn(), len(step.Params)+offset+len(keywords)-1)
+			}
+			param, err := action.convert(idx, keyword, actionType.In(index))
+			if err != nil {
+				return step, v.handle.NewException("Step [%s]: cannot convert [%s] to %s: %s",
+					step.Text, keyword, actionType.In(index), err.Error())
+			}
+			step.Params = append(step.Params, param)
 		}
 	}
+	if len(step.Params)+offset != actionType.NumIn() {
+		return step, v.handle.NewException("Step [%s]: action accepts %d params, but got %d",
+			step.Text, actionType.NumIn(), len(step.Params)+offset)
+	}
 
 	return step, nil
 }
 
 
 // Start to run Go2Test framework
+// Only parse/setup errors are returned, use RunWithResult() to know which scenarios failed
 // @params:
 //     path: test files location ( where *.feature is )
-//     tags: filter by @tag
+//     tags: filter by tag expressions, e.g. "@smoke and not @slow", matched if any of them matched
 func (v *Go2Test) Run(path string, tags []string) *Exception {
+	_, err := v.RunWithResult(path, tags)
+	return err
+}
+
+
+// Start to run Go2Test framework and collect the results
+// @params:
+//     path: test files location ( where *.feature is )
+//     tags: filter by tag expressions, see Run()
+// @returns:
+//     (*Result): Features, scenarios and steps with their status, nil if parse/setup failed
+//     (*Exception): Parse/setup error
+func (v *Go2Test) RunWithResult(path string, tags []string) (*Result, *Exception) {
+
+	features, err := v.loadFeatures(path, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	result := v.startResult(features)
+	v.handle.notify(func(l Listener) { l.RunStarted(result) })
+	// No feature runs if BeforeSuite hooks failed
+	// BeforeSuite and AfterSuite hooks share a handle, so the Buffer keeps what they set up
+	suiteHandle := v.newHandle(nil)
+	if exception := v.runHooks(hookBeforeSuite, suiteHandle, nil); exception != nil {
+		result.Errors = append(result.Errors, exception)
+	} else {
+		for _, feature := range features {
+			feature.run(v.handle, v.concurrency, func(scenario *Scenario) {
+				v.runScenario(feature, scenario)
+			})
+		}
+	}
+	if exception := v.runHooks(hookAfterSuite, suiteHandle, nil); exception != nil {
+		result.Errors = append(result.Errors, exception)
+	}
+	result.finish()
+	v.handle.notify(func(l Listener) { l.RunFinished(result) })
+	v.writeSnippetFile(result)
+
+	if len(result.Errors) > 0 {
+		return result, v.handle.NewException("%s", result.errorsMessage())
+	}
+	return result, nil
+}
+
+
+// Run scenario with its own *Handle, or skip it in dry-run mode
+// @params:
+//     feature: The feature of scenario
+//     scenario: The scenario to run
+func (v *Go2Test) runScenario(feature *Feature, scenario *Scenario) {
+	handle := v.newHandle(feature)
+	if v.dryRun {
+		scenario.Skip(handle)
+		return
+	}
+	scenario.Run(handle)
+}
+
+
+// Search *.feature and create *Feature for each of them
+// @params:
+//     path: test files location ( where *.feature is )
+//     tags: filter by tag expressions, see Run()
+// @returns:
+//     ([]*Feature): Features need to run
+//     (*Exception): Parse/setup error
+func (v *Go2Test) loadFeatures(path string, tags []string) ([]*Feature, *Exception) {
 
 	v.handle.clean()
+	v.problems = make([]*Exception, 0)
+
+	filter, err := compileTagFilter(tags)
+	if err != nil {
+		return nil, v.handle.NewException("%s", err.Error())
+	}
 
 	log.Infof("Search *.feature by [%s]", path)
 	files, err := filepath.Glob(path)
 	if err != nil {
-		return v.handle.NewException(err.Error())
+		return nil, v.handle.NewException("%s", err.Error())
+	}
+
+	// Hook files first, their hooks apply to every feature
+	hookFiles, files, exp := v.splitHookFiles(files)
+	if exp != nil {
+		return nil, exp
+	}
+	v.shared = &hookLibrary{paths: map[*ghk.Step]string{}}
+	for _, p := range hookFiles {
+		log.Infof("- %s (hooks)", p)
+		if exp := v.loadHookFile(p); exp != nil {
+			log.Errorf("Reading %s", p)
+			return nil, exp
+		}
 	}
 
 	features := make([]*Feature, 0)
 	for _, p := range files {
 		log.Infof("- %s", p)
-		feature, err := v.createFeature(p, tags)
+		feature, err := v.createFeature(p, filter)
 		if err != nil {
 			log.Errorf("Reading %s", p)
-			return err
+			if !v.dryRun {
+				return nil, err
+			}
+			if err.Feature == nil {
+				err.Feature = &Feature{Path: p}
+			}
+			v.problems = append(v.problems, err)
+			continue
 		}
 		if feature != nil {
 			features = append(features, feature)
 		}
 	}
+	return features, nil
+}
 
-	for _, feature := range features {
-		feature.Run(v.handle)
+
+// Create new *Result of the run
+// It knows the actions and hook files, for Cucumber Messages
+// @params:
+//     features: Features going to run
+// @returns:
+//     (*Result): new *Result
+func (v *Go2Test) startResult(features []*Feature) *Result {
+	result := newResult(features)
+	result.Errors = v.problems
+	result.actions = v.actions
+	if v.shared != nil {
+		result.hookSources = v.shared.sources
 	}
+	return result
+}
 
-	return nil
+
+// Split files into hook files and feature files
+// Hook files are *.hooks.feature and the files added by AddHookFile(), in the order they are found
+// @params:
+//     files: Files found by the path of run
+// @returns:
+//     ([]string): Hook files
+//     ([]string): Feature files
+//     (*Exception): Invalid pattern of hook file
+func (v *Go2Test) splitHookFiles(files []string) ([]string, []string, *Exception) {
+	hookFiles := make([]string, 0)
+	isHookFile := make(map[string]bool)
+	addHookFile := func(p string) {
+		if !isHookFile[filepath.Clean(p)] {
+			isHookFile[filepath.Clean(p)] = true
+			hookFiles = append(hookFiles, p)
+		}
+	}
+
+	for _, pattern := range v.hookFiles {
+		matched, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, v.handle.NewException("Invalid hook file [%s]: %s", pattern, err.Error())
+		}
+		if len(matched) == 0 {
+			return nil, nil, v.handle.NewException("Hook file [%s] not found", pattern)
+		}
+		for _, p := range matched {
+			addHookFile(p)
+		}
+	}
+	for _, p := range files {
+		if strings.HasSuffix(p, ".hooks.feature") {
+			addHookFile(p)
+		}
+	}
+
+	features := make([]string, 0, len(files))
+	for _, p := range files {
+		if !isHookFile[filepath.Clean(p)] {
+			features = append(features, p)
+		}
+	}
+	return hookFiles, features, nil
 }
 
 
+// Steps of hooks matched the scenario's name, hooks with tag expressions are not matched
+// Please use HookList.Steps() to match tags too
 func GetHookSteps(lib HookList, s_name string) ([]*ghk.Step) {
+	return lib.Steps(s_name, nil)
+}
+
+// Steps of hooks matched the scenario, in the order of hooks
+// @params:
+//    name: Name of scenario
+//    tags: Tags of scenario, include the inherited ones
+// @returns:
+//    ([]*ghk.Step): Steps of all matched hooks
+func (v HookList) Steps(name string, tags []string) []*ghk.Step {
 	ret := make([]*ghk.Step, 0)
-	lib_size := len(lib)
-	for i:=0; i<lib_size; i++ {
-		hook := lib[i]
-		if len(hook.Regex.FindStringSubmatch(s_name)) != 0 {
-			step_size := len(hook.Steps)
-			for j:=0; j<step_size; j++ {
-				ret = append(ret, hook.Steps[j])
+	for _, hook := range v {
+		if hook.Match(name, tags) {
+			ret = append(ret, hook.Steps...)
+		}
+	}
+	return ret
+}
+
+
+// Line number of *ghk.Location, 0 if unknown
+func lineOf(location *ghk.Location) int {
+	if location == nil {
+		return 0
+	}
+	return location.Line
+}
+
+
+// Merge tags without duplicates, keep the order
+func mergeTags(tags ...[]string) []string {
+	ret := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, list := range tags {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				ret = append(ret, tag)
 			}
 		}
 	}
@@ -769,3 +2071,11 @@ func GetHookSteps(lib HookList, s_name string) ([]*ghk.Step) {
 }
 
 
+// Names of []*ghk.Tag
+func tagNames(gTags []*ghk.Tag) []string {
+	names := make([]string, 0, len(gTags))
+	for _, gTag := range gTags {
+		names = append(names, gTag.Name)
+	}
+	return names
+}
@@ -1,11 +1,20 @@
 package go2test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"testing"
 	log "github.com/Sirupsen/logrus"
-//	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 //
 //
@@ -184,4 +193,1536 @@ func Test_009(t *testing.T) {
 		}
 		t.Fail()
 	}
-}
\ No newline at end of file
+}
+
+func Test_010(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string){
+		log.Infof("Name: %s", name)
+	})
+	go2test.AddAction("^Failed$", func(handle *Handle){
+		panic("err")
+	})
+	result, exp := go2test.RunWithResult("./examples/background.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if !result.Failed() {
+		t.Errorf("Result should be failed")
+	}
+	if result.ScenarioCount.Passed != 1 || result.ScenarioCount.Failed != 1 {
+		t.Errorf("Unexpected scenario counts: %+v", result.ScenarioCount)
+	}
+	if result.StepCount.Passed != 4 || result.StepCount.Failed != 1 || result.StepCount.Skipped != 3 {
+		t.Errorf("Unexpected step counts: %+v", result.StepCount)
+	}
+	failed := result.Features[0].Scenarios[1]
+	if failed.Exception == nil || failed.Exception.Message != "err" {
+		t.Errorf("Unexpected exception: %+v", failed.Exception)
+	}
+}
+
+func Test_011(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string){
+		log.Infof("Name: %s", name)
+	})
+	go2test.AddAction("^Failed$", func(handle *Handle){
+		handle.ThrowException("Failed on purpose")
+	})
+	result, exp := go2test.RunWithResult("./examples/background.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	buf := new(bytes.Buffer)
+	if err := result.WriteJUnit(buf); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	xml := buf.String()
+	for _, expected := range []string{
+		`<testsuite name="BG" tests="2" failures="1" skipped="0"`,
+		`<testcase name="Scenario1" classname="BG"`,
+		`<failure message="[Failed] Failed on purpose" type="Exception">`,
+		`[SKIPPED] Name ScenarioC`,
+	} {
+		if !strings.Contains(xml, expected) {
+			t.Errorf("Missing [%s] in:\n%s", expected, xml)
+		}
+	}
+}
+
+func Test_012(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Params$", func(handle *Handle, values []map[string]string){
+		handle.Attach("text/plain", []byte(values[0]["Title1"]))
+	})
+	result, exp := go2test.RunWithResult("./examples/table.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := result.WriteCucumberJSON(buf); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	features := make([]map[string]interface{}, 0)
+	if err := json.Unmarshal(buf.Bytes(), &features); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	step := features[0]["elements"].([]interface{})[0].(map[string]interface{})["steps"].([]interface{})[0].(map[string]interface{})
+	if step["keyword"] != "Given " || step["line"] != float64(4) {
+		t.Errorf("Unexpected step: %+v", step)
+	}
+	if len(step["rows"].([]interface{})) != 3 || len(step["embeddings"].([]interface{})) != 1 {
+		t.Errorf("Unexpected step arguments: %+v", step)
+	}
+
+	buf.Reset()
+	if err := result.WriteMessages(buf); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		envelope := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil || len(envelope) != 1 {
+			t.Errorf("Invalid envelope: %s", line)
+		}
+	}
+	if !strings.Contains(lines[len(lines)-1], `"testRunFinished":{"success":true`) {
+		t.Errorf("Unexpected last envelope: %s", lines[len(lines)-1])
+	}
+	checkMessages(t, buf.String())
+}
+
+// Check ids of Cucumber Messages are linked: pickles => gherkinDocument, test steps => pickles && stepDefinitions
+func checkMessages(t *testing.T, ndjson string) {
+	t.Helper()
+	astIds := make(map[string]bool)
+	var collect func(node interface{})
+	collect = func(node interface{}) {
+		switch value := node.(type) {
+		case map[string]interface{}:
+			if id, ok := value["id"].(string); ok {
+				astIds[id] = true
+			}
+			for _, child := range value {
+				collect(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				collect(child)
+			}
+		}
+	}
+
+	type envelope struct {
+		Meta            *struct{ ProtocolVersion string }
+		Source          *struct{ URI, Data, MediaType string }
+		GherkinDocument map[string]interface{}
+		Pickle          *struct {
+			ID, URI    string
+			AstNodeIds []string
+			Tags       []struct{ Name, AstNodeId string }
+			Steps      []struct {
+				ID         string
+				AstNodeIds []string
+			}
+		}
+		StepDefinition  *struct {
+			ID      string
+			Pattern struct{ Source, Type string }
+		}
+		TestCase        *struct {
+			PickleId  string
+			TestSteps []struct {
+				PickleStepId      string
+				StepDefinitionIds []string
+			}
+		}
+	}
+	pickleIds := make(map[string]bool)
+	definitionIds := make(map[string]bool)
+	sources := make(map[string]bool)
+	testCases := 0
+	for _, line := range strings.Split(strings.TrimSpace(ndjson), "\n") {
+		var e envelope
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid envelope: %s", line)
+		}
+		switch {
+		case e.Meta != nil:
+			if e.Meta.ProtocolVersion != messagesProtocolVersion {
+				t.Errorf("Unexpected meta: %s", line)
+			}
+		case e.Source != nil:
+			if e.Source.Data == "" || e.Source.MediaType != "text/x.cucumber.gherkin+plain" {
+				t.Errorf("Unexpected source: %s", line)
+			}
+			sources[e.Source.URI] = true
+		case e.GherkinDocument != nil:
+			if !sources[e.GherkinDocument["uri"].(string)] {
+				t.Errorf("gherkinDocument without source: %s", line)
+			}
+			collect(e.GherkinDocument)
+		case e.Pickle != nil:
+			if !sources[e.Pickle.URI] || len(e.Pickle.AstNodeIds) == 0 {
+				t.Errorf("Pickle is not linked to gherkinDocument: %s", line)
+			}
+			ids := append([]string{}, e.Pickle.AstNodeIds...)
+			for _, tag := range e.Pickle.Tags {
+				ids = append(ids, tag.AstNodeId)
+			}
+			for _, step := range e.Pickle.Steps {
+				if len(step.AstNodeIds) == 0 {
+					t.Errorf("Pickle step [%s] is not linked to gherkinDocument", step.ID)
+				}
+				ids = append(ids, step.AstNodeIds...)
+				pickleIds[step.ID] = true
+			}
+			for _, id := range ids {
+				if !astIds[id] {
+					t.Errorf("Unknown astNodeId [%s] of pickle %s", id, e.Pickle.ID)
+				}
+			}
+			pickleIds[e.Pickle.ID] = true
+		case e.StepDefinition != nil:
+			if e.StepDefinition.Pattern.Source == "" || e.StepDefinition.Pattern.Type == "" {
+				t.Errorf("Unexpected stepDefinition: %s", line)
+			}
+			definitionIds[e.StepDefinition.ID] = true
+		case e.TestCase != nil:
+			testCases++
+			if !pickleIds[e.TestCase.PickleId] {
+				t.Errorf("Unknown pickle of test case: %s", line)
+			}
+			for _, step := range e.TestCase.TestSteps {
+				if !pickleIds[step.PickleStepId] || len(step.StepDefinitionIds) == 0 {
+					t.Errorf("Test step is not linked: %s", line)
+				}
+				for _, id := range step.StepDefinitionIds {
+					if !definitionIds[id] {
+						t.Errorf("Unknown stepDefinition [%s]: %s", id, line)
+					}
+				}
+			}
+		}
+	}
+	if testCases == 0 {
+		t.Errorf("No test case in messages")
+	}
+}
+
+type eventListener struct {
+	BaseListener
+	events []string
+}
+
+func (v *eventListener) ScenarioStarted(handle *Handle, scenario *Scenario) {
+	v.events = append(v.events, "scenario:" + scenario.Name)
+}
+
+func (v *eventListener) StepPassed(handle *Handle, step *Step) {
+	v.events = append(v.events, "pass:" + step.Text)
+}
+
+func (v *eventListener) StepFailed(handle *Handle, step *Step, exception *Exception) {
+	v.events = append(v.events, "fail:" + step.Text)
+}
+
+func (v *eventListener) StepSkipped(handle *Handle, step *Step) {
+	v.events = append(v.events, "skip:" + step.Text)
+}
+
+func (v *eventListener) RunFinished(result *Result) {
+	v.events = append(v.events, "finished")
+}
+
+func Test_013(t *testing.T) {
+	listener := new(eventListener)
+	go2test := NewGo2Test()
+	go2test.SetListeners(listener)
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string){})
+	go2test.AddAction("^Failed$", func(handle *Handle){
+		panic("err")
+	})
+	exp := go2test.Run("./examples/background.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	expected := []string{
+		"scenario:Scenario1", "pass:Name Backgound", "pass:Name Scenario1",
+		"scenario:Scenario2", "pass:Name Backgound", "pass:Name Scenario2", "fail:Failed",
+		"skip:Name ScenarioA", "skip:Name ScenarioB", "skip:Name ScenarioC",
+		"finished",
+	}
+	if strings.Join(listener.events, ",") != strings.Join(expected, ",") {
+		t.Errorf("Unexpected events: %v", listener.events)
+	}
+}
+
+func Test_014(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string){
+		log.Infof("Name: %s", name)
+	})
+	go2test.AddAction("^LineNum: (.*)$", func(handle *Handle, no string){})
+	if os.Getenv("G2T_SUBPROCESS") == "1" {
+		for _, path := range []string{"./examples/hook.feature", "./examples/tags.feature"} {
+			feature := go2test.RunT(t, path, make([]string, 0)).Features[0]
+			for _, scenario := range feature.Scenarios {
+				t.Logf("Scenario [%s/%s] is %s", feature.Name, scenario.Name, statusName(scenario.Status))
+			}
+			t.Logf("Feature [%s] is %s", feature.Name, statusName(feature.Status))
+		}
+		return
+	}
+
+	result := go2test.RunT(t, "./examples/hook.feature", make([]string, 0))
+	if result.ScenarioCount.Passed != 1 {
+		t.Errorf("Unexpected scenario counts: %+v", result.ScenarioCount)
+	}
+
+	// Scenarios filtered out by -run are skipped
+	out, err := runSubprocess("^Test_014$/^Test_Scenario_Tags$/^Tag1$")
+	if err != nil || strings.Contains(out, "waiting") {
+		t.Errorf("Filtered scenarios should be skipped:\n%s", out)
+	}
+	for _, expected := range []string{
+		"Scenario [Test Scenario Tags/Tag1] is passed",
+		"Scenario [Test Scenario Tags/Tag2] is skipped",
+		"Feature [Test Scenario Tags] is passed",
+		"Scenario [Test Hook/S1] is skipped",
+		"Feature [Test Hook] is skipped",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Missing [%s] in:\n%s", expected, out)
+		}
+	}
+}
+
+// Run the tests matched by pattern in a new process with G2T_SUBPROCESS=1, to check what RunT reports to its *testing.T
+func runSubprocess(pattern string) (string, error) {
+	cmd := exec.Command(os.Args[0], "-test.run="+pattern, "-test.v")
+	cmd.Env = append(os.Environ(), "G2T_SUBPROCESS=1")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func Test_015(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Count (.+), ratio (.+), enabled (.+), wait (.+)$",
+		func(handle *Handle, count int, ratio float64, enabled bool, wait time.Duration){
+			handle.Buffer["sum"] = float64(count) + ratio + wait.Minutes()
+			handle.Buffer["enabled"] = enabled
+		})
+	result, exp := go2test.RunWithResult("./examples/typed.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if result.ScenarioCount.Passed != 1 {
+		t.Errorf("Unexpected scenario counts: %+v", result.ScenarioCount)
+	}
+
+	go2test = NewGo2Test()
+	go2test.AddAction("^Count (.+), ratio (.+), enabled (.+), wait (.+)$",
+		func(handle *Handle, count int, ratio int, enabled bool, wait time.Duration){})
+	_, exp = go2test.RunWithResult("./examples/typed.feature", make([]string, 0))
+	if exp == nil || !strings.Contains(exp.Message, "cannot convert [0.5] to int") {
+		t.Errorf("Unexpected exception: %+v", exp)
+	}
+}
+
+func Test_016(t *testing.T) {
+	go2test := NewGo2Test()
+	invalid := map[string]interface{}{
+		"^Name(.*)$": "not a func",
+		"^Name (.*)$": func(name string){},
+		"^Name  (.*)$": func(handle *Handle){},
+		"^Name (.*) (.*)$": func(handle *Handle, name string){},
+		"^Count (.*)$": func(handle *Handle, count chan int){},
+		"^Table (.*)$": func(handle *Handle, table map[string]string, name string){},
+	}
+	for reg, action := range invalid {
+		if exp := go2test.AddAction(reg, action); exp == nil {
+			t.Errorf("AddAction [%s] should fail", reg)
+		}
+	}
+	valid := map[string]interface{}{
+		"^Name(.*)$": func(handle *Handle, name string){},
+		"^Params$": func(handle *Handle, values []string){},
+		"^Count (.*), ratio (.*)$": func(handle *Handle, values []map[string]string, count int, ratio float64){},
+	}
+	for reg, action := range valid {
+		if exp := go2test.AddAction(reg, action); exp != nil {
+			t.Errorf("AddAction [%s] failed: %s", reg, exp.Message)
+		}
+	}
+}
+
+func Test_017(t *testing.T) {
+	failed := errors.New("name is not allowed")
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string) error {
+		if strings.TrimSpace(name) == "Scenario2" {
+			return failed
+		}
+		return nil
+	})
+	go2test.AddAction("^Failed$", func(handle *Handle){})
+	result, exp := go2test.RunWithResult("./examples/background.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if result.ScenarioCount.Passed != 1 || result.ScenarioCount.Failed != 1 {
+		t.Errorf("Unexpected scenario counts: %+v", result.ScenarioCount)
+	}
+	exception := result.Features[0].Scenarios[1].Exception
+	if exception == nil || !errors.Is(exception, failed) || exception.Step.Text != "Name Scenario2" {
+		t.Errorf("Unexpected exception: %+v", exception)
+	}
+}
+
+func Test_018(t *testing.T) {
+	cases := map[string]bool{
+		"@a": true,
+		"not @a": false,
+		"@a and @c": false,
+		"@a and not @c": true,
+		"(@c or @b) and not @d": true,
+		"not (@a or @c)": false,
+		"@c or not @b and @a": false,
+		"@a and not @timeout(30s)": true,
+		"(@timeout(1m) or @b)": true,
+	}
+	for text, expected := range cases {
+		expr, err := ParseTagExpression(text)
+		if err != nil {
+			t.Errorf("%s", err.Error())
+			continue
+		}
+		if expr.Evaluate([]string{"@a", "@b"}) != expected {
+			t.Errorf("[%s] should be %v", text, expected)
+		}
+	}
+	for _, text := range []string{"", "@a and", "(@a or @b", "@a @b", "a and @b", "not", "@timeout(30s"} {
+		if _, err := ParseTagExpression(text); err == nil {
+			t.Errorf("[%s] should be invalid", text)
+		}
+	}
+
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name (.*)$", func(handle *Handle, name string){})
+	go2test.AddAction("^Name: (.*)$", func(handle *Handle, name string){})
+	go2test.AddAction("^LineNum: (.*)$", func(handle *Handle, no int){})
+	filters := map[string]int{
+		"not @Tag2": 3,
+		"@Tag1 and not @Tag2": 1,
+		"(@Tag2 or @Tag3) and not @Tag1": 3,
+	}
+	for filter, expected := range filters {
+		result, exp := go2test.RunWithResult("./examples/tags.feature", []string{filter})
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+		if result.ScenarioCount.Passed != expected {
+			t.Errorf("[%s] should run %d scenarios: %+v", filter, expected, result.ScenarioCount)
+		}
+	}
+}
+
+func Test_019(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name (.*)$", func(handle *Handle, name string){})
+	go2test.AddAction("^Name: (.*)$", func(handle *Handle, name string){})
+	filters := map[string][]string{
+		"@Smoke": {"Untagged", "Slow", "Outline | Fast List | 0", "Outline | Slow List | 0", "Outline | Slow List | 1"},
+		"@Smoke and not @Slow": {"Untagged", "Outline | Fast List | 0"},
+		"@Outline and @Slow": {"Outline | Slow List | 0", "Outline | Slow List | 1"},
+		"@Fast": {"Outline | Fast List | 0"},
+	}
+	for filter, expected := range filters {
+		result, exp := go2test.RunWithResult("./examples/inherit.feature", []string{filter})
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+		names := make([]string, 0)
+		for _, scenario := range result.Features[0].Scenarios {
+			names = append(names, scenario.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("[%s] should run %v, but ran %v", filter, expected, names)
+		}
+	}
+	result, _ := go2test.RunWithResult("./examples/inherit.feature", []string{"@Tag1"})
+	if len(result.Features) != 0 {
+		t.Errorf("Feature without matched scenario should be dropped")
+	}
+}
+
+func Test_020(t *testing.T) {
+	var running, maximum int32
+	go2test := NewGo2Test()
+	go2test.SetConcurrency(3)
+	go2test.AddAction("^Remember (.*)$", func(handle *Handle, name string){
+		if _, ok := handle.Buffer["name"]; ok && name == "P0" {
+			handle.ThrowException("Buffer leaked from other scenario")
+		}
+		handle.Buffer["name"] = name
+	})
+	go2test.AddAction("^Wait and check (.*)$", func(handle *Handle, name string){
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maximum)
+			if now <= old || atomic.CompareAndSwapInt32(&maximum, old, now) {
+				break
+			}
+		}
+		if name == "P4" && now != 1 {
+			handle.ThrowException("@serial scenario ran with others")
+		}
+		// Barrier, the parallel scenarios wait until all of them are running
+		deadline := time.Now().Add(5 * time.Second)
+		for name != "P4" && atomic.LoadInt32(&maximum) < 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if handle.Buffer["name"] != name {
+			handle.ThrowException("Buffer is shared: %v", handle.Buffer["name"])
+		}
+	})
+	result, exp := go2test.RunWithResult("./examples/parallel.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if result.ScenarioCount.Passed != 4 {
+		t.Errorf("Unexpected scenario counts: %+v", result.ScenarioCount)
+	}
+	if maximum != 3 {
+		t.Errorf("Scenarios should run in parallel, max running: %d", maximum)
+	}
+}
+
+func Test_021(t *testing.T) {
+	cancelled := make(chan bool, 2)
+	go2test := NewGo2Test()
+	go2test.SetStepTimeout(200 * time.Millisecond)
+	go2test.AddAction("^Sleep (.+)$", func(handle *Handle, ctx context.Context, d time.Duration){
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			cancelled <- true
+		}
+	})
+	result, exp := go2test.RunWithResult("./examples/timeout.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	for _, scenario := range result.Features[0].Scenarios {
+		statuses := make([]int, 0)
+		for _, step := range scenario.Steps {
+			statuses = append(statuses, step.Status)
+		}
+		if fmt.Sprint(statuses) != fmt.Sprint([]int{G2T_STATUS_PASS, G2T_STATUS_FAIL, G2T_STATUS_SKIP}) {
+			t.Errorf("Unexpected step status of [%s]: %v", scenario.Name, statuses)
+		}
+	}
+	if msg := result.Features[0].Scenarios[0].Exception.Message; !strings.HasPrefix(msg, "Step timed out") {
+		t.Errorf("Unexpected exception: %s", msg)
+	}
+	if msg := result.Features[0].Scenarios[1].Exception.Message; !strings.HasPrefix(msg, "Scenario timed out") {
+		t.Errorf("Unexpected exception: %s", msg)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Errorf("Context of action is not cancelled")
+		}
+	}
+
+	// Filter by the timeout tag
+	for _, tags := range [][]string{{"@timeout(100ms)"}, {"(@timeout(100ms) or @slow) and not @timeout(1s)"}} {
+		result, exp = go2test.RunWithResult("./examples/timeout.feature", tags)
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+		if len(result.Features[0].Scenarios) != 1 || result.Features[0].Scenarios[0].Name != "Scenario Timeout" {
+			t.Errorf("Unexpected scenarios of %v: %+v", tags, result.Features[0].Scenarios)
+		}
+	}
+}
+
+func Test_022(t *testing.T) {
+	ran := false
+	go2test := NewGo2Test()
+	go2test.SetDryRun(true)
+	go2test.AddAction("^Name(.*)$", func(handle *Handle, name string){
+		ran = true
+	})
+	go2test.AddAction("^Count (.+), ratio (.+), enabled (.+), wait (.+)$",
+		func(handle *Handle, count int, ratio int, enabled bool, wait time.Duration){
+			ran = true
+		})
+	result, exp := go2test.RunWithResult("./examples/*.feature", make([]string, 0))
+	if exp == nil || result == nil {
+		t.Fatalf("Dry-run should report errors")
+	}
+	if ran {
+		t.Errorf("Action should not run in dry-run mode")
+	}
+	if !result.Failed() || result.StepCount.Skipped+result.StepCount.Undefined != result.StepCount.Total {
+		t.Errorf("Unexpected step counts: %+v", result.StepCount)
+	}
+	files := make(map[string]bool)
+	for _, err := range result.Errors {
+		files[err.Feature.Path] = true
+	}
+	for _, file := range []string{"examples/background.feature", "examples/typed.feature", "examples/list.feature"} {
+		if !files[file] {
+			t.Errorf("Errors of %s are not reported: %s", file, exp.Message)
+		}
+	}
+	for _, expected := range []string{
+		"Undefined step [Failed]:\n        examples/background.feature:11",
+		"examples/typed.feature:4: Step [Count 3, ratio 0.5, enabled true, wait 1m30s]: cannot convert [0.5] to int",
+	} {
+		if !strings.Contains(exp.Message, expected) {
+			t.Errorf("Missing [%s] in: %s", expected, exp.Message)
+		}
+	}
+}
+
+func Test_023(t *testing.T) {
+	names := make([]string, 0)
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name (.*)$", func(handle *Handle, name string){
+		names = append(names, name)
+	})
+	go2test.AddAction("^Name Scenario([A-C])$", func(handle *Handle, name string){
+	})
+	result, exp := go2test.RunWithResult("examples/background.feature", make([]string, 0))
+	if exp == nil {
+		t.Fatalf("Undefined steps should be reported")
+	}
+	if strings.Join(names, ",") != "Backgound,Scenario1" {
+		t.Errorf("Other scenarios should run: %v", names)
+	}
+	scenarios := result.Features[0].Scenarios
+	if scenarios[0].Status != G2T_STATUS_PASS || scenarios[1].Status != G2T_STATUS_UNDEFINED {
+		t.Errorf("Unexpected status: %d %d", scenarios[0].Status, scenarios[1].Status)
+	}
+	undefined := result.UndefinedSteps()
+	if len(undefined) != 4 || undefined[0].Text != "Failed" || undefined[1].Text != "Name ScenarioA" {
+		t.Fatalf("Unexpected undefined steps: %+v", undefined)
+	}
+	if undefined[0].Status != G2T_STATUS_UNDEFINED || undefined[0].Locations[0] != "examples/background.feature:11" {
+		t.Errorf("Unexpected undefined step: %+v", undefined[0])
+	}
+	if result.StepCount.Ambiguous != 3 || result.StepCount.Undefined != 1 || len(result.Errors) != 4 {
+		t.Errorf("Unexpected step counts: %+v", result.StepCount)
+	}
+	if !strings.Contains(exp.Message, "Ambiguous step [Name ScenarioA]:\n        examples/background.feature:12") {
+		t.Errorf("Unexpected message: %s", exp.Message)
+	}
+}
+
+func Test_024(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Count 3, ratio 0.5, enabled true, wait 1m30s": "^Count (-?\\d+), ratio (-?\\d+\\.\\d+), enabled true, wait 1m30s$ [int float64]",
+		"User \"Tom\" logs in as 'admin' on P2": "^User \"([^\"]*)\" logs in as '([^']*)' on P2$ [string string]",
+		"Sum (a+b)": "^Sum \\(a\\+b\\)$ []",
+	} {
+		regex, types := snippetRegex(text)
+		if fmt.Sprintf("%s %v", regex, types) != expected {
+			t.Errorf("Unexpected snippet of [%s]: %s %v", text, regex, types)
+		}
+	}
+
+	snippets := new(bytes.Buffer)
+	for _, file := range []string{"examples/table.feature", "examples/typed.feature"} {
+		result, _ := NewGo2Test().RunWithResult(file, make([]string, 0))
+		if err := result.WriteSnippets(snippets); err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+	}
+	for _, expected := range []string{
+		"go2test.AddAction(`^Params$`, func(handle *Handle, table []map[string]string) {",
+		"go2test.AddAction(`^Count (-?\\d+), ratio (-?\\d+\\.\\d+), enabled true, wait 1m30s$`, func(handle *Handle, arg1 int, arg2 float64) {",
+	} {
+		if !strings.Contains(snippets.String(), expected) {
+			t.Errorf("Missing [%s] in: %s", expected, snippets.String())
+		}
+	}
+
+	// The snippet must be accepted as it is
+	dir, err := ioutil.TempDir("", "go2test")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	snippetFile := filepath.Join(dir, "snippets.go.txt")
+	go2test := NewGo2Test()
+	go2test.SetSnippetFile(snippetFile)
+	go2test.RunWithResult("examples/typed.feature", make([]string, 0))
+	if data, err := ioutil.ReadFile(snippetFile); err != nil || !strings.HasPrefix(string(data), "go2test.AddAction(") {
+		t.Errorf("Unexpected snippet file: %q %v", data, err)
+	}
+	if exp := go2test.AddAction(`^Count (-?\d+), ratio (-?\d+\.\d+), enabled true, wait 1m30s$`,
+		func(handle *Handle, arg1 int, arg2 float64) {}); exp != nil {
+		t.Errorf("%s", exp.Message)
+	}
+	if _, exp := go2test.RunWithResult("examples/typed.feature", make([]string, 0)); exp != nil {
+		t.Errorf("%s", exp.Message)
+	}
+	// Snippets of previous run are removed
+	if _, err := os.Stat(snippetFile); !os.IsNotExist(err) {
+		t.Errorf("Snippet file should be removed: %v", err)
+	}
+}
+
+func Test_039(t *testing.T) {
+	step := &Step{Text: "Name Tom", Status: G2T_STATUS_WAIT}
+	step.Skip()
+	if step.Status != G2T_STATUS_SKIP {
+		t.Errorf("Step should be skipped: %+v", step)
+	}
+	undefined := &Step{Text: "Unknown", Status: G2T_STATUS_UNDEFINED}
+	undefined.Skip()
+	if undefined.Status != G2T_STATUS_UNDEFINED {
+		t.Errorf("Undefined step should keep its status: %+v", undefined)
+	}
+}
+
+func Test_025(t *testing.T) {
+	names := make([]string, 0)
+	name := func(handle *Handle, name string){
+		names = append(names, name)
+	}
+	scenario := func(handle *Handle, id string){
+		names = append(names, "#"+id)
+	}
+
+	// Ambiguous, every matched action is listed in the order they are added
+	go2test := NewGo2Test()
+	go2test.AddAction("^Name (.*)$", name)
+	go2test.AddAction("^Name Scenario(.)$", scenario)
+	_, exp := go2test.RunWithResult("examples/background.feature", make([]string, 0))
+	if exp == nil {
+		t.Fatalf("Ambiguous steps should be reported")
+	}
+	for _, expected := range []string{
+		"Ambiguous step [Name Scenario1]:\n        examples/background.feature:7\n",
+		"        `^Name (.*)$` at ",
+		"go2test_test.go:",
+	} {
+		if !strings.Contains(exp.Message, expected) {
+			t.Errorf("Missing [%s] in: %s", expected, exp.Message)
+		}
+	}
+	if strings.Index(exp.Message, "`^Name (.*)$`") > strings.Index(exp.Message, "`^Name Scenario(.)$`") {
+		t.Errorf("Actions should be listed in order: %s", exp.Message)
+	}
+
+	// Priority wins
+	go2test = NewGo2Test()
+	go2test.AddAction("^Name (.*)$", name)
+	go2test.AddActionWithPriority("^Name Scenario(.)$", 1, scenario)
+	go2test.AddAction("^Failed$", func(handle *Handle){})
+	names = names[:0]
+	if _, exp := go2test.RunWithResult("examples/background.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(names, ",") != "Backgound,#1,Backgound,#2,#A,#B,#C" {
+		t.Errorf("Unexpected actions: %v", names)
+	}
+
+	// The most specific wins
+	go2test = NewGo2Test()
+	go2test.SetMostSpecificMatch(true)
+	go2test.AddAction("^Name (.*)$", name)
+	go2test.AddAction("^Name Scenario(.)$", scenario)
+	go2test.AddAction("^Failed$", func(handle *Handle){})
+	names = names[:0]
+	if _, exp := go2test.RunWithResult("examples/background.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(names, ",") != "Backgound,#1,Backgound,#2,#A,#B,#C" {
+		t.Errorf("Unexpected actions: %v", names)
+	}
+
+	// Nested groups are not counted twice
+	go2test = NewGo2Test()
+	go2test.SetMostSpecificMatch(true)
+	go2test.AddAction("^Name (.*)$", name)
+	go2test.AddAction(`^Name Scen((ario)\d)$`, func(handle *Handle, id string, word string){
+		names = append(names, "#"+id)
+	})
+	go2test.AddAction("^Failed$", func(handle *Handle){})
+	names = names[:0]
+	if _, exp := go2test.RunWithResult("examples/background.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(names, ",") != "Backgound,#ario1,Backgound,#ario2,ScenarioA,ScenarioB,ScenarioC" {
+		t.Errorf("Unexpected actions: %v", names)
+	}
+}
+
+func Test_026(t *testing.T) {
+	got := make([]string, 0)
+	go2test := NewGo2Test()
+	for _, exp := range []*Exception{
+		go2test.AddStep("{word}'s name: {string}", func(handle *Handle, person string, name string){
+			got = append(got, person+"="+name)
+		}),
+		go2test.AddStep("{word} has {int} cucumber(s) in/on the basket", func(handle *Handle, name string, count int){
+			got = append(got, fmt.Sprintf("%s:%d", name, count))
+		}),
+		go2test.AddStep("the ratio is {float}", func(handle *Handle, ratio interface{}){
+			got = append(got, fmt.Sprintf("%T", ratio))
+		}),
+	} {
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	if _, exp := go2test.RunWithResult("examples/expression.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(got, ",") != "Person1=Tom Smith,Tom:3,Tom:1,float64" {
+		t.Errorf("Unexpected params: %v", got)
+	}
+
+	if regex, _, _ := compileExpression(`{} \(a\/b\) is {word}`, builtinParameterTypes); regex.String() != `^(.*) \(a/b\) is ([^\s]+)$` {
+		t.Errorf("Unexpected regex: %s", regex)
+	}
+	// "/" without text on one side is literal
+	for expr, text := range map[string]string{"{int}/{int}": "3/4", "path a/ b": "path a/ b", "a//b": "a//b"} {
+		if regex, _, err := compileExpression(expr, builtinParameterTypes); err != nil || !regex.MatchString(text) {
+			t.Errorf("[%s] should match [%s]: %v %v", expr, text, regex, err)
+		}
+	}
+	// Text of expression is not a format
+	if exp := go2test.AddStep("100% {int", func(handle *Handle, n int){}); exp == nil ||
+		exp.Message != "missing } in [100% {int]" {
+		t.Errorf("Unexpected error: %+v", exp)
+	}
+	if exp := go2test.AddStep("{color} is red", func(handle *Handle, color string){}); exp == nil ||
+		!strings.Contains(exp.Message, "undefined parameter type {color}") {
+		t.Errorf("Undefined parameter type should fail: %+v", exp)
+	}
+	if exp := go2test.AddStep("{int} and {int}", func(handle *Handle, a int){}); exp == nil {
+		t.Errorf("Params should match the expression")
+	}
+}
+
+type testUser struct {
+	Name string
+}
+
+func Test_027(t *testing.T) {
+	users := map[string]*testUser{"Tom": {Name: "Tom"}}
+	got := make([]string, 0)
+	go2test := NewGo2Test()
+	for _, exp := range []*Exception{
+		go2test.AddParameterType("user", "[A-Z][a-z]+", func(name string) (*testUser, error) {
+			if user, ok := users[name]; ok {
+				return user, nil
+			}
+			return nil, fmt.Errorf("no user %s", name)
+		}),
+		go2test.AddParameterType("money", `\$\d+(?:\.\d+)?`, func(text string) float64 {
+			var money float64
+			fmt.Sscanf(text, "$%f", &money)
+			return money
+		}),
+		go2test.AddParameterType("date", `\d{4}-\d{2}-\d{2}`, func(text string) (time.Time, error) {
+			return time.Parse("2006-01-02", text)
+		}),
+		go2test.AddStep("{user} pays {money} on {date}", func(handle *Handle, user *testUser, money float64, date time.Time){
+			got = append(got, fmt.Sprintf("%s %.2f %s", user.Name, money, date.Format("Jan 2")))
+		}),
+		// Typed params of regex use the registered types too
+		go2test.AddAction("^(.+) is a member since (.+)$", func(handle *Handle, user *testUser, date time.Time){
+			got = append(got, fmt.Sprintf("%s %d", user.Name, date.Year()))
+		}),
+	} {
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	if _, exp := go2test.RunWithResult("examples/parameter.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(got, ",") != "Tom 12.50 Feb 29,Tom 2020" {
+		t.Errorf("Unexpected params: %v", got)
+	}
+
+	// Transformer errors fail the step
+	delete(users, "Tom")
+	if _, exp := go2test.RunWithResult("examples/parameter.feature", make([]string, 0)); exp == nil ||
+		!strings.Contains(exp.Message, "no user Tom") {
+		t.Errorf("Transformer error should be reported: %+v", exp)
+	}
+
+	for _, exp := range []*Exception{
+		go2test.AddParameterType("user", ".+", func(text string) string { return text }),
+		go2test.AddParameterType("pair", "(.+),(.+)", func(text string) string { return text }),
+		go2test.AddParameterType("bad", ".+", func(a, b string) string { return a }),
+		go2test.AddStep("{money} is {user}", func(handle *Handle, money int, user *testUser){}),
+		// Fewer params than capture groups
+		go2test.AddAction("^(a) (b)$", func(handle *Handle){}),
+	} {
+		if exp == nil {
+			t.Errorf("Invalid parameter type or step should fail")
+		}
+	}
+}
+
+func Test_028(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+	got := make([]string, 0)
+	go2test := NewGo2Test()
+	for _, exp := range []*Exception{
+		go2test.AddAction("^Text$", func(handle *Handle, text string){
+			got = append(got, text)
+		}),
+		go2test.AddAction("^Document$", func(handle *Handle, doc *DocString){
+			got = append(got, doc.ContentType+":"+doc.Content)
+		}),
+		go2test.AddAction("^User in json$", func(handle *Handle, u user){
+			got = append(got, fmt.Sprintf("%s=%d", u.Name, u.Age))
+		}),
+		go2test.AddAction("^User in yaml$", func(handle *Handle, u *user){
+			got = append(got, fmt.Sprintf("%s=%d", u.Name, u.Age))
+		}),
+		go2test.AddAction("^Map in json$", func(handle *Handle, m map[string]interface{}){
+			got = append(got, fmt.Sprintf("%v", m["admin"]))
+		}),
+	} {
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	result, exp := go2test.RunWithResult("examples/docstring.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(got, ",") != "Hello <name>,markdown:# Title,Tom=30,Jerry=3,true" {
+		t.Errorf("Unexpected DocStrings: %v", got)
+	}
+	buf := new(bytes.Buffer)
+	result.WriteCucumberJSON(buf)
+	compact := new(bytes.Buffer)
+	json.Compact(compact, buf.Bytes())
+	if !strings.Contains(compact.String(), `"doc_string":{"content_type":"json","value":"{\"name\": \"Tom\", \"age\": 30}","line":13}`) {
+		t.Errorf("DocString is not reported: %s", compact.String())
+	}
+
+	// The DocString can't fit
+	go2test = NewGo2Test()
+	go2test.AddAction("^Text$", func(handle *Handle){})
+	go2test.AddAction("^Document$", func(handle *Handle, u user){})
+	go2test.SetDryRun(true)
+	result, exp = go2test.RunWithResult("examples/docstring.feature", make([]string, 0))
+	for _, expected := range []string{
+		"Step [Text]: action does not accept DocString",
+		"Step [Document]: cannot convert DocString to go2test.user: cannot unmarshal DocString of content type [markdown]",
+	} {
+		if exp == nil || !strings.Contains(exp.Message, expected) {
+			t.Errorf("Missing [%s] in: %+v", expected, exp)
+		}
+	}
+	buf.Reset()
+	result.WriteSnippets(buf)
+	if !strings.Contains(buf.String(), "go2test.AddAction(`^User in json$`, func(handle *Handle, doc string) {") {
+		t.Errorf("Unexpected snippets: %s", buf.String())
+	}
+}
+
+type testRow struct {
+	Title     string `g2t:"Title1"`
+	FirstName string
+	Age       int
+	Admin     bool
+	Ignored   string `g2t:"-"`
+}
+
+type testAccount struct {
+	Owner   *testUser
+	Balance float64
+	Expire  time.Duration
+}
+
+func Test_029(t *testing.T) {
+	got := make([]string, 0)
+	go2test := NewGo2Test()
+	go2test.AddParameterType("user", ".+", func(name string) *testUser { return &testUser{Name: name} })
+	for _, exp := range []*Exception{
+		go2test.AddAction("^Users$", func(handle *Handle, rows []testRow){
+			for _, row := range rows {
+				got = append(got, fmt.Sprintf("%s %s %d %v", row.Title, row.FirstName, row.Age, row.Admin))
+			}
+		}),
+		go2test.AddAction("^Account$", func(handle *Handle, account *testAccount){
+			got = append(got, fmt.Sprintf("%s %.1f %s", account.Owner.Name, account.Balance, account.Expire))
+		}),
+	} {
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	if _, exp := go2test.RunWithResult("examples/struct.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if strings.Join(got, ",") != "Mr Tom 30 true,Ms Ann 25 false,Tom 12.5 1h0m0s" {
+		t.Errorf("Unexpected rows: %v", got)
+	}
+
+	// Tables which can't fit
+	type badRow struct {
+		Title1 string
+		Age    uint
+	}
+	go2test = NewGo2Test()
+	go2test.SetDryRun(true)
+	go2test.AddAction("^Users$", func(handle *Handle, rows []*badRow){})
+	go2test.AddAction("^Account$", func(handle *Handle, rows []string){})
+	_, exp := go2test.RunWithResult("examples/struct.feature", make([]string, 0))
+	for _, expected := range []string{
+		"Step [Users]: cannot convert table to []*go2test.badRow: no field of go2test.badRow for column [First name]",
+		"Step [Account]: cannot convert table to []string: table has 2 columns, []string needs 1",
+	} {
+		if exp == nil || !strings.Contains(exp.Message, expected) {
+			t.Errorf("Missing [%s] in: %+v", expected, exp)
+		}
+	}
+}
+
+func Test_030(t *testing.T) {
+	var prices *DataTable
+	go2test := NewGo2Test()
+	go2test.AddAction("^Prices$", func(handle *Handle, table *DataTable){
+		prices = table
+	})
+	go2test.AddAction("^Expect$", func(handle *Handle, table DataTable) error {
+		actual := make([][]string, 0)
+		for _, row := range prices.Raw() {
+			actual = append(actual, row[:2])
+		}
+		return table.Diff(NewDataTable(actual))
+	})
+	if _, exp := go2test.RunWithResult("examples/datatable.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+
+	if strings.Join(prices.Headers(), ",") != "Item,Price,Stock" || len(prices.Rows()) != 2 {
+		t.Errorf("Unexpected table: %v", prices.Raw())
+	}
+	if prices.Cell(1, 0) != "Banana" || prices.Value(0, "Stock") != "true" || prices.Cell(5, 0) != "" {
+		t.Errorf("Unexpected cells: %v", prices.Raw())
+	}
+	if price, err := prices.CellFloat(1, 1); err != nil || price != 0.25 {
+		t.Errorf("Unexpected price: %v %v", price, err)
+	}
+	if stock, err := prices.CellBool(0, 2); err != nil || !stock {
+		t.Errorf("Unexpected stock: %v %v", stock, err)
+	}
+	if _, err := prices.CellInt(0, 1); err == nil {
+		t.Errorf("1.5 is not int")
+	}
+	if maps := prices.AsMaps(); maps[1]["Price"] != "0.25" {
+		t.Errorf("Unexpected maps: %v", maps)
+	}
+	if transposed := prices.Transpose(); strings.Join(transposed.Headers(), ",") != "Item,Apple,Banana" ||
+		transposed.Value(0, "Banana") != "0.25" {
+		t.Errorf("Unexpected transposed table: %v", transposed.Raw())
+	}
+
+	expected := NewDataTable([][]string{{"Item", "Price"}, {"Apple", "1.5"}, {"Banana", "0.25"}})
+	actual := NewDataTable([][]string{{"Item", "Price"}, {"Apple", "1.5"}, {"Banana", "0.3"}, {"Kiwi", "2"}})
+	err := expected.Diff(actual)
+	if err == nil {
+		t.Fatalf("Tables should be different")
+	}
+	diff := strings.Join([]string{
+		"Tables are different:",
+		"  | Item   | Price |",
+		"  | Apple  | 1.5   |",
+		"- | Banana | 0.25  |",
+		"+ | Banana | 0.3   |",
+		"+ | Kiwi   | 2     |",
+	}, "\n")
+	if err.Error() != diff {
+		t.Errorf("Unexpected diff:\n%s", err.Error())
+	}
+}
+
+func Test_031(t *testing.T) {
+	logs := make([]string, 0)
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){
+		logs = append(logs, handle.Scenario.Name+": "+text)
+	})
+	go2test.AddAction("^Fail (.*)$", func(handle *Handle, text string) error {
+		return errors.New(text + " failed")
+	})
+	result, exp := go2test.RunWithResult("examples/cleanup.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+
+	// After hooks run in reverse order of priority, even if the scenario or a hook failed
+	expected := []string{
+		"Clean failed: Create user",
+		"Clean failed: Delete user",
+		"Clean failed: Close connection",
+		"Clean passed: Create user",
+		"Clean passed: Delete user",
+		"Clean passed: Close connection",
+		"Clean outline |  | 0: Tom",
+		"Clean outline |  | 0: Delete user",
+		"Clean outline |  | 0: Close connection",
+	}
+	if strings.Join(logs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected steps:\n%s", strings.Join(logs, "\n"))
+	}
+
+	failed, passed := result.Features[0].Scenarios[0], result.Features[0].Scenarios[1]
+	if failed.Status != G2T_STATUS_FAIL || failed.Exception.Message != "step failed" ||
+		failed.AfterException.Message != "cleanup failed" || failed.Steps[2].Status != G2T_STATUS_SKIP {
+		t.Errorf("Unexpected failed scenario: %+v", failed)
+	}
+	if passed.Status != G2T_STATUS_FAIL || passed.Exception != nil || passed.AfterException.Step.Text != "Fail cleanup" {
+		t.Errorf("Unexpected passed scenario: %+v", passed)
+	}
+	if len(passed.Steps) != 1 || len(passed.AfterSteps) != 3 || passed.AfterSteps[2].Status != G2T_STATUS_PASS {
+		t.Errorf("Unexpected steps of scenario: %+v", passed.AllSteps())
+	}
+
+	buf := new(bytes.Buffer)
+	result.WriteJUnit(buf)
+	for _, expected := range []string{
+		`<failure message="[Fail step] step failed" type="Exception">`,
+		`<failure message="[after hook: Fail cleanup] cleanup failed" type="AfterHook">`,
+		"After hook [Fail cleanup] failed: cleanup failed",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Missing [%s] in JUnit", expected)
+		}
+	}
+
+	// After hooks wait for the action timed out, they do not share the handle with it
+	read := ""
+	go2test = NewGo2Test()
+	go2test.AddAction("^Write result slowly$", func(handle *Handle, ctx context.Context){
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		handle.Buffer["result"] = "written"
+	})
+	go2test.AddAction("^Read result$", func(handle *Handle){
+		read, _ = handle.Buffer["result"].(string)
+	})
+	result, exp = go2test.RunWithResult("examples/cleanup_timeout.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	scenario := result.Features[0].Scenarios[0]
+	if !strings.HasPrefix(scenario.Exception.Message, "Scenario timed out") || scenario.AfterSteps[0].Status != G2T_STATUS_PASS {
+		t.Errorf("Unexpected timed out scenario: %+v", scenario)
+	}
+	if read != "written" {
+		t.Errorf("After hook should run after the timed out action returned, read [%s]", read)
+	}
+}
+
+func Test_032(t *testing.T) {
+	logs := make([]string, 0)
+	record := func(text string) {
+		logs = append(logs, text)
+	}
+	newGo2Test := func() *Go2Test {
+		logs = logs[:0]
+		go2test := NewGo2Test()
+		go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){
+			record(text)
+		})
+		go2test.BeforeSuite(0, func(handle *Handle){ record("BeforeSuite") })
+		go2test.AfterSuite(0, func(handle *Handle){ record("AfterSuite") })
+		go2test.BeforeFeature(0, "@api", func(handle *Handle, feature *Feature){
+			record("BeforeFeature " + feature.Name)
+		})
+		go2test.BeforeFeature(0, "@ui", func(handle *Handle, feature *Feature){ record("Never") })
+		go2test.AfterFeature(0, "", func(handle *Handle, feature *Feature){
+			record("AfterFeature " + feature.Name)
+		})
+		go2test.BeforeScenario(2, "", func(handle *Handle, scenario *Scenario){
+			record("BeforeScenario(2) " + scenario.Name)
+		})
+		go2test.BeforeScenario(1, "", func(handle *Handle, scenario *Scenario){
+			handle.Buffer["scenario"] = scenario.Name
+			record("BeforeScenario(1) " + scenario.Name)
+		})
+		go2test.AfterScenario(1, "", func(handle *Handle, scenario *Scenario){
+			record("AfterScenario(1) " + handle.Buffer["scenario"].(string))
+		})
+		go2test.AfterScenario(2, "@db", func(handle *Handle, scenario *Scenario){
+			record("AfterScenario(2) " + scenario.Name)
+		})
+		go2test.BeforeStep(0, "not @db", func(handle *Handle, step *Step){
+			record("BeforeStep " + step.Text)
+		})
+		go2test.AfterStep(0, "not @db", func(handle *Handle, step *Step){
+			record("AfterStep " + statusName(step.Status))
+		})
+		return go2test
+	}
+
+	go2test := newGo2Test()
+	if exp := go2test.BeforeStep(0, "@db and", func(handle *Handle, step *Step){}); exp == nil {
+		t.Errorf("Invalid tags should fail")
+	}
+	if _, exp := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	expected := []string{
+		"BeforeSuite",
+		"BeforeFeature Lifecycle",
+		"BeforeScenario(1) First",
+		"BeforeScenario(2) First",
+		"BeforeStep Log one", "one", "AfterStep passed",
+		"BeforeStep Log two", "two", "AfterStep passed",
+		"AfterScenario(1) First",
+		"BeforeScenario(1) Second",
+		"BeforeScenario(2) Second",
+		"three",
+		"AfterScenario(2) Second",
+		"AfterScenario(1) Second",
+		"AfterFeature Lifecycle",
+		"AfterSuite",
+	}
+	if strings.Join(logs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected hooks:\n%s", strings.Join(logs, "\n"))
+	}
+
+	// Before and after hooks of suite and feature share the handle
+	go2test = newGo2Test()
+	go2test.BeforeSuite(1, func(handle *Handle){ handle.Buffer["suite"] = "suite data" })
+	go2test.AfterSuite(1, func(handle *Handle){ record(fmt.Sprint(handle.Buffer["suite"])) })
+	go2test.BeforeFeature(1, "", func(handle *Handle, feature *Feature){ handle.Buffer["feature"] = "feature data" })
+	go2test.AfterFeature(1, "", func(handle *Handle, feature *Feature){ record(fmt.Sprint(handle.Buffer["feature"])) })
+	if _, exp := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	for _, expected := range []string{"\nfeature data\n", "\nsuite data\n"} {
+		if !strings.Contains(strings.Join(logs, "\n"), expected) {
+			t.Errorf("Missing [%s] in hooks:\n%s", strings.TrimSpace(expected), strings.Join(logs, "\n"))
+		}
+	}
+
+	// Failed hooks
+	go2test = newGo2Test()
+	go2test.BeforeScenario(0, "@db", func(handle *Handle, scenario *Scenario){
+		handle.ThrowException("no db")
+	})
+	go2test.AfterStep(0, "", func(handle *Handle, step *Step){
+		if step.Text == "Log two" {
+			panic("bad step")
+		}
+	})
+	result, _ := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	first, second := result.Features[0].Scenarios[0], result.Features[0].Scenarios[1]
+	if first.Status != G2T_STATUS_FAIL || first.Exception.Step != first.Steps[1] ||
+		first.Exception.Message != "AfterStep hook: bad step" {
+		t.Errorf("AfterStep should fail the step: %+v", first.Exception)
+	}
+	if second.Status != G2T_STATUS_FAIL || second.Exception.Message != "BeforeScenario hook: no db" ||
+		second.Steps[0].Status != G2T_STATUS_SKIP {
+		t.Errorf("BeforeScenario should fail the scenario: %+v", second.Exception)
+	}
+	if !strings.Contains(strings.Join(logs, "\n"), "AfterScenario(2) Second") {
+		t.Errorf("AfterScenario should run: %v", logs)
+	}
+
+	go2test = newGo2Test()
+	go2test.BeforeFeature(0, "", func(handle *Handle, feature *Feature){
+		panic("no feature")
+	})
+	result, _ = go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	for _, scenario := range result.Features[0].Scenarios {
+		if scenario.Status != G2T_STATUS_FAIL || scenario.Exception.Message != "BeforeFeature hook: no feature" {
+			t.Errorf("BeforeFeature should fail scenarios: %+v", scenario.Exception)
+		}
+	}
+
+	go2test = newGo2Test()
+	go2test.BeforeSuite(1, func(handle *Handle){
+		panic("no suite")
+	})
+	_, exp := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	if exp == nil || !strings.Contains(exp.Message, "\n    BeforeSuite hook: no suite") {
+		t.Errorf("BeforeSuite should fail the run: %+v", exp)
+	}
+	if strings.Join(logs, ",") != "BeforeSuite,AfterSuite" {
+		t.Errorf("Nothing should run: %v", logs)
+	}
+}
+
+func Test_033(t *testing.T) {
+	logs := make([]string, 0)
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){
+		logs = append(logs, text)
+	})
+	if exp := go2test.BeforeScenario(0, "tag:@readonly", func(handle *Handle, scenario *Scenario){
+		logs = append(logs, "Go hook " + scenario.Name)
+	}); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if _, exp := go2test.RunWithResult("examples/taghook.feature", make([]string, 0)); exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	expected := []string{
+		"Open write connection", "Load fixtures", "Write row", "Close api",
+		"Go hook Read", "Load fixtures", "Read row", "Close api",
+		"Open write connection", "Load fixtures", "Use db", "Close api",
+		"Use plain", "Close api",
+	}
+	if strings.Join(logs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected hooks:\n%s", strings.Join(logs, "\n"))
+	}
+
+	if _, exp := go2test.RunWithResult("examples/taghook_invalid.feature", make([]string, 0));
+		exp == nil || !strings.Contains(exp.Message, "Invalid Hook title [@before / tag:@db and 100%]: invalid tag expression [@db and 100%]") {
+		t.Errorf("Invalid tag expression of hook should fail: %+v", exp)
+	}
+
+	tags, _ := ParseTagExpression("@db")
+	hook := &Hook{Tags: tags}
+	if !hook.Match("Any", []string{"@api", "@db"}) || hook.Match("db", []string{"@api"}) {
+		t.Errorf("Tag hook should match by tags only")
+	}
+	if len(GetHookSteps(HookList{hook}, "db")) != 0 {
+		t.Errorf("GetHookSteps should not match tag hooks")
+	}
+}
+
+func Test_034(t *testing.T) {
+	logs := make([]string, 0)
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){
+		logs = append(logs, text)
+	})
+	go2test.AddHookFile("examples/shared/extra/*.feature")
+	result, exp := go2test.RunWithResult("examples/shared/*.feature", make([]string, 0))
+	if exp != nil {
+		t.Fatalf("%s", exp.Message)
+	}
+	if len(result.Features) != 2 || result.Features[0].Name != "Orders" || result.Features[1].Name != "Users" {
+		t.Fatalf("Hook files should not run as features: %+v", result.Features)
+	}
+	expected := []string{
+		"Shared background", "Orders background",
+		"Shared before(1)", "Orders before(1)", "Orders before(2)", "Shared before(3)",
+		"Create order",
+		"Extra after(2)", "Orders after(1)", "Shared after(1)",
+		"Shared background",
+		"Shared before(1)",
+		"List users",
+		"Extra after(2)", "Shared after(1)",
+	}
+	if strings.Join(logs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected hooks:\n%s", strings.Join(logs, "\n"))
+	}
+
+	go2test = NewGo2Test()
+	go2test.AddHookFile("examples/shared/users.feature")
+	if _, exp = go2test.RunWithResult("examples/shared/*.feature", make([]string, 0));
+		exp == nil || !strings.Contains(exp.Message, "Hook file must only have hook scenarios, found [List users]") {
+		t.Errorf("Hook file with scenarios should fail: %+v", exp)
+	}
+
+	go2test = NewGo2Test()
+	go2test.AddHookFile("examples/shared/missing.feature")
+	if _, exp = go2test.RunWithResult("examples/shared/*.feature", make([]string, 0));
+		exp == nil || !strings.Contains(exp.Message, "Hook file [examples/shared/missing.feature] not found") {
+		t.Errorf("Missing hook file should fail: %+v", exp)
+	}
+
+	// Undefined steps of hook files are reported in the hook file
+	go2test = NewGo2Test()
+	go2test.AddAction("^Log (Orders|Users|Extra|Create|List)(.*)$", func(handle *Handle, name string, text string){})
+	go2test.AddHookFile("examples/shared/extra/*.feature")
+	result, _ = go2test.RunWithResult("examples/shared/*.feature", make([]string, 0))
+	locations := map[string]string{}
+	for _, undefined := range result.UndefinedSteps() {
+		locations[undefined.Text] = strings.Join(undefined.Locations, ",")
+	}
+	for text, location := range map[string]string{
+		"Log Shared background": "examples/shared/common.hooks.feature:4",
+		"Log Shared before(1)": "examples/shared/common.hooks.feature:7",
+		"Log Shared after(1)": "examples/shared/common.hooks.feature:13",
+	} {
+		if locations[text] != location {
+			t.Errorf("Undefined step [%s] should be at %s: %+v", text, location, locations)
+		}
+	}
+	found := false
+	for _, err := range result.Errors {
+		if err.Step != nil && err.Step.Text == "Log Shared after(1)" {
+			found = true
+			if err.Location() != "examples/shared/common.hooks.feature:13" {
+				t.Errorf("Unexpected location of shared step: %s", err.Location())
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Undefined shared step is not collected: %+v", result.Errors)
+	}
+}
+
+func Test_035(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){})
+	go2test.AfterScenario(0, "@db", func(handle *Handle, scenario *Scenario){
+		panic("close failed")
+	})
+	if os.Getenv("G2T_SUBPROCESS") == "1" {
+		go2test.RunT(t, "examples/lifecycle.feature", make([]string, 0))
+		return
+	}
+
+	result, _ := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	buf := new(bytes.Buffer)
+	if err := result.WriteJUnit(buf); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	for _, expected := range []string{
+		`<failure message="[after hook] AfterScenario hook: close failed" type="AfterHook">`,
+		"After hook failed: AfterScenario hook: close failed",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Missing [%s] in JUnit:\n%s", expected, buf.String())
+		}
+	}
+
+	out, err := runSubprocess("^Test_035$")
+	if err == nil || strings.Contains(out, "panic") ||
+		!strings.Contains(out, "AfterScenario hook: close failed") || !strings.Contains(out, "--- PASS: Test_035/Lifecycle/First") {
+		t.Errorf("RunT should fail the scenario by the hook:\n%s", out)
+	}
+}
+
+func Test_036(t *testing.T) {
+	type account struct {
+		Owner   string
+		Balance float64
+		Expire  time.Duration
+	}
+	got := make([]string, 0)
+	go2test := NewGo2Test()
+	for _, exp := range []*Exception{
+		go2test.AddParameterType("upper", "[a-z]+", func(text string) string { return strings.ToUpper(text) }),
+		go2test.AddParameterType("money", `\$\d+`, func(text string) float64 { return 0 }),
+		go2test.AddParameterType("wait", `\d+`, func(text string) time.Duration { return 0 }),
+		// Built-in types are not taken over by parameter types
+		go2test.AddAction("^Count (.+), ratio (.+), enabled (.+), wait (.+)$",
+			func(handle *Handle, count int, ratio float64, enabled bool, wait time.Duration){
+				got = append(got, fmt.Sprintf("%d %.1f %s", count, ratio, wait))
+			}),
+		go2test.AddAction("^Users$", func(handle *Handle, table []map[string]string){}),
+		go2test.AddAction("^Account$", func(handle *Handle, a account){
+			got = append(got, fmt.Sprintf("%s %.1f %s", a.Owner, a.Balance, a.Expire))
+		}),
+	} {
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	for _, path := range []string{"examples/typed.feature", "examples/struct.feature"} {
+		if _, exp := go2test.RunWithResult(path, make([]string, 0)); exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+	}
+	if strings.Join(got, ",") != "3 0.5 1m30s,Tom 12.5 1h0m0s" {
+		t.Errorf("Unexpected params: %v", got)
+	}
+}
+
+func Test_037(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){})
+	go2test.AfterFeature(0, "", func(handle *Handle, feature *Feature){
+		panic("report failed")
+	})
+	if os.Getenv("G2T_SUBPROCESS") == "1" {
+		go2test.RunT(t, "examples/lifecycle.feature", make([]string, 0))
+		return
+	}
+
+	result, _ := go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	buf := new(bytes.Buffer)
+	if err := result.WriteJUnit(buf); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	for _, expected := range []string{
+		`<testsuite name="Lifecycle" tests="3" failures="1"`,
+		`<testcase name="Feature hooks" classname="Lifecycle"`,
+		`<failure message="AfterFeature hook: report failed" type="FeatureHook">`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Missing [%s] in JUnit:\n%s", expected, buf.String())
+		}
+	}
+
+	out, err := runSubprocess("^Test_037$")
+	if err == nil || !strings.Contains(out, "AfterFeature hook: report failed") ||
+		!strings.Contains(out, "--- FAIL: Test_037/Lifecycle ") {
+		t.Errorf("RunT should fail the feature by the hook:\n%s", out)
+	}
+
+	// Scenarios report the failure of BeforeFeature hooks
+	go2test = NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){})
+	go2test.BeforeFeature(0, "", func(handle *Handle, feature *Feature){
+		panic("setup failed")
+	})
+	result, _ = go2test.RunWithResult("examples/lifecycle.feature", make([]string, 0))
+	buf.Reset()
+	result.WriteJUnit(buf)
+	if !strings.Contains(buf.String(), `<testsuite name="Lifecycle" tests="2" failures="2"`) {
+		t.Errorf("Unexpected JUnit:\n%s", buf.String())
+	}
+}
+
+func Test_038(t *testing.T) {
+	go2test := NewGo2Test()
+	go2test.AddAction("^Log (.*)$", func(handle *Handle, text string){})
+	go2test.AddStep("Log {word} {word}", func(handle *Handle, a string, b string){})
+	go2test.SetMostSpecificMatch(true)
+	for _, path := range []string{"examples/shared/*.feature", "examples/taghook.feature"} {
+		result, exp := go2test.RunWithResult(path, make([]string, 0))
+		if exp != nil {
+			t.Fatalf("%s", exp.Message)
+		}
+		buf := new(bytes.Buffer)
+		if err := result.WriteMessages(buf); err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+		checkMessages(t, buf.String())
+		for _, expected := range []string{
+			`"uri":"examples/shared/common.hooks.feature"`,
+			`"pattern":{"source":"^Log (.*)$","type":"REGULAR_EXPRESSION"}`,
+			`"pattern":{"source":"Log {word} {word}","type":"CUCUMBER_EXPRESSION"}`,
+		} {
+			if path == "examples/taghook.feature" && strings.Contains(expected, "common.hooks") {
+				continue
+			}
+			if !strings.Contains(buf.String(), expected) {
+				t.Errorf("Missing [%s] in messages of %s", expected, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package go2test
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kinds of lifecycle hook, every before kind is followed by its after kind
+const (
+	hookBeforeSuite = iota
+	hookAfterSuite
+	hookBeforeFeature
+	hookAfterFeature
+	hookBeforeScenario
+	hookAfterScenario
+	hookBeforeStep
+	hookAfterStep
+)
+
+var hookNames = []string{
+	"BeforeSuite", "AfterSuite",
+	"BeforeFeature", "AfterFeature",
+	"BeforeScenario", "AfterScenario",
+	"BeforeStep", "AfterStep",
+}
+
+// ----------------------------------------------------------------------------------
+// @name: lifecycleHook
+// Hook registered in Go code, e.g. by Go2Test.BeforeScenario()
+// @values
+//    - kind: hookBeforeSuite|hookAfterSuite|...
+//    - priority: Before hooks run from low to high, after hooks from high to low
+//    - filter: Tag expression, nil if no filter
+//    - call: Calls the func of user with the *Feature, *Scenario or *Step of handle
+// ----------------------------------------------------------------------------------
+type lifecycleHook struct {
+	kind        int
+	priority    int
+	filter      TagExpression
+	call        func(handle *Handle)
+}
+
+// Whether the kind is an after hook
+func isAfterHook(kind int) bool {
+	return kind%2 == 1
+}
+
+
+// Add hook runs before all features
+// If it fails, no feature runs and the run returns the error
+// @params:
+//    priority: Hooks with lower priority run first, the same priority by the order they are added
+//    fn: The hook, panic or Handle.ThrowException() to fail
+func (v *Go2Test) BeforeSuite(priority int, fn func(handle *Handle)) {
+	v.addHook(hookBeforeSuite, priority, nil, fn)
+}
+
+// Add hook runs after all features, even if some failed
+// @params:
+//    priority: Hooks with higher priority run first
+//    fn: The hook, panic or Handle.ThrowException() to fail
+func (v *Go2Test) AfterSuite(priority int, fn func(handle *Handle)) {
+	v.addHook(hookAfterSuite, priority, nil, fn)
+}
+
+// Add hook runs before each feature
+// If it fails, every scenario of the feature fails with its error
+// @params:
+//    priority: Hooks with lower priority run first
+//    tags: Tag expression matched with the feature's tags, empty for all features
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) BeforeFeature(priority int, tags string, fn func(handle *Handle, feature *Feature)) *Exception {
+	return v.addFilteredHook(hookBeforeFeature, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Feature)
+	})
+}
+
+// Add hook runs after each feature
+// @params:
+//    priority: Hooks with higher priority run first
+//    tags: Tag expression matched with the feature's tags, empty for all features
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) AfterFeature(priority int, tags string, fn func(handle *Handle, feature *Feature)) *Exception {
+	return v.addFilteredHook(hookAfterFeature, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Feature)
+	})
+}
+
+// Add hook runs before each scenario, with the scenario's *Handle
+// If it fails, steps of the scenario are skipped, but after hooks still run
+// @params:
+//    priority: Hooks with lower priority run first
+//    tags: Tag expression matched with the scenario's tags, empty for all scenarios
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) BeforeScenario(priority int, tags string, fn func(handle *Handle, scenario *Scenario)) *Exception {
+	return v.addFilteredHook(hookBeforeScenario, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Scenario)
+	})
+}
+
+// Add hook runs after each scenario, even if it failed
+// Its failure is kept in Scenario.AfterException
+// @params:
+//    priority: Hooks with higher priority run first
+//    tags: Tag expression matched with the scenario's tags, empty for all scenarios
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) AfterScenario(priority int, tags string, fn func(handle *Handle, scenario *Scenario)) *Exception {
+	return v.addFilteredHook(hookAfterScenario, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Scenario)
+	})
+}
+
+// Add hook runs before each step, its failure fails the step
+// @params:
+//    priority: Hooks with lower priority run first
+//    tags: Tag expression matched with the scenario's tags, empty for all steps
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) BeforeStep(priority int, tags string, fn func(handle *Handle, step *Step)) *Exception {
+	return v.addFilteredHook(hookBeforeStep, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Step)
+	})
+}
+
+// Add hook runs after each step, even if it failed, step.Status tells the result
+// Its failure fails the step if the step passed
+// @params:
+//    priority: Hooks with higher priority run first
+//    tags: Tag expression matched with the scenario's tags, empty for all steps
+//    fn: The hook, panic or Handle.ThrowException() to fail
+// @returns:
+//    (*Exception): Invalid tag expression
+func (v *Go2Test) AfterStep(priority int, tags string, fn func(handle *Handle, step *Step)) *Exception {
+	return v.addFilteredHook(hookAfterStep, priority, tags, func(handle *Handle) {
+		fn(handle, handle.Step)
+	})
+}
+
+
+// Add hook with tag expression
+// The "tag:" prefix of hook scenarios in *.feature is accepted too
+func (v *Go2Test) addFilteredHook(kind int, priority int, tags string, call func(handle *Handle)) *Exception {
+	filter, err := compileTagFilter([]string{strings.TrimPrefix(strings.TrimSpace(tags), "tag:")})
+	if err != nil {
+		return v.handle.NewException("Invalid tags of %s hook: %s", hookNames[kind], err.Error())
+	}
+	v.addHook(kind, priority, filter, call)
+	return nil
+}
+
+func (v *Go2Test) addHook(kind int, priority int, filter TagExpression, call func(handle *Handle)) {
+	v.hooks = append(v.hooks, &lifecycleHook{kind: kind, priority: priority, filter: filter, call: call})
+}
+
+
+// Run hooks of the kind, nothing runs in dry-run mode
+// Before hooks stop at the first failure, after hooks all run
+// @params:
+//    kind: hookBeforeSuite|hookAfterSuite|...
+//    handle: *Handle passed to hooks
+//    tags: Tags of feature or scenario, matched with filters of hooks
+// @returns:
+//    (*Exception): The first failure, nil if all passed
+func (v *Go2Test) runHooks(kind int, handle *Handle, tags []string) *Exception {
+	if v == nil || v.dryRun {
+		return nil
+	}
+	hooks := make([]*lifecycleHook, 0)
+	for _, hook := range v.hooks {
+		if hook.kind == kind && matchTags(hook.filter, tags) {
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if isAfterHook(kind) {
+			return hooks[i].priority > hooks[j].priority
+		}
+		return hooks[i].priority < hooks[j].priority
+	})
+
+	var first *Exception
+	for _, hook := range hooks {
+		exception := handle.callHook(hook)
+		if exception == nil {
+			continue
+		}
+		if first == nil {
+			first = exception
+		}
+		if !isAfterHook(kind) {
+			break
+		}
+	}
+	return first
+}
+
+// Call hook, and turn its panic into *Exception
+func (v *Handle) callHook(hook *lifecycleHook) (exception *Exception) {
+	defer func() {
+		if err := recover(); err != nil {
+			exception = v.toException(err)
+			exception.Message = hookNames[hook.kind] + " hook: " + exception.Message
+		}
+	}()
+	hook.call(v)
+	return nil
+}
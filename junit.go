@@ -0,0 +1,170 @@
+package go2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------------
+// JUnit XML document
+// Feature => <testsuite>, Scenario => <testcase>, failure of feature hooks => <testcase name="Feature hooks">
+// ----------------------------------------------------------------------------------
+type junitTestSuites struct {
+	XMLName     xml.Name          `xml:"testsuites"`
+	Tests       int               `xml:"tests,attr"`
+	Failures    int               `xml:"failures,attr"`
+	Skipped     int               `xml:"skipped,attr"`
+	Time        string            `xml:"time,attr"`
+	Suites      []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name        string            `xml:"name,attr"`
+	Tests       int               `xml:"tests,attr"`
+	Failures    int               `xml:"failures,attr"`
+	Skipped     int               `xml:"skipped,attr"`
+	Time        string            `xml:"time,attr"`
+	Timestamp   string            `xml:"timestamp,attr,omitempty"`
+	TestCases   []*junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name        string            `xml:"name,attr"`
+	ClassName   string            `xml:"classname,attr"`
+	File        string            `xml:"file,attr,omitempty"`
+	Time        string            `xml:"time,attr"`
+	Failure     *junitFailure     `xml:"failure,omitempty"`
+	Skipped     *junitSkipped     `xml:"skipped,omitempty"`
+	SystemOut   string            `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message     string            `xml:"message,attr"`
+	Type        string            `xml:"type,attr"`
+	Content     string            `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message     string            `xml:"message,attr,omitempty"`
+}
+
+// Format duration as JUnit seconds
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// Create <testcase> from *Scenario
+// Every step is listed in <system-out> with its status
+func newJUnitTestCase(feature *Feature, scenario *Scenario) *junitTestCase {
+	tc := new(junitTestCase)
+	tc.Name = scenario.Name
+	tc.ClassName = feature.Name
+	tc.File = feature.Path
+	tc.Time = junitTime(scenario.Duration)
+
+	lines := make([]string, 0, len(scenario.Steps)+len(scenario.AfterSteps))
+	for _, step := range scenario.AllSteps() {
+		lines = append(lines, fmt.Sprintf("[%s] %s", strings.ToUpper(statusName(step.Status)), step.Text))
+	}
+	tc.SystemOut = strings.Join(lines, "\n")
+
+	switch scenario.Status {
+	case G2T_STATUS_PASS:
+	case G2T_STATUS_FAIL, G2T_STATUS_UNDEFINED, G2T_STATUS_AMBIGUOUS:
+		tc.Failure = new(junitFailure)
+		tc.Failure.Type = "Exception"
+		if scenario.Status != G2T_STATUS_FAIL {
+			tc.Failure.Type = strings.Title(statusName(scenario.Status))
+		}
+		if scenario.Exception != nil {
+			tc.Failure.Message = scenario.Exception.Message
+			tc.Failure.Content = scenario.Exception.Stack
+			if scenario.Exception.Step != nil {
+				tc.Failure.Message = fmt.Sprintf("[%s] %s", scenario.Exception.Step.Text, scenario.Exception.Message)
+			}
+		}
+		// Failure of after hook is reported after the scenario's
+		// AfterScenario hooks in Go have no step, their message tells the hook
+		if after := scenario.AfterException; after != nil {
+			text := fmt.Sprintf("After hook failed: %s\n%s", after.Message, after.Stack)
+			message := fmt.Sprintf("[after hook] %s", after.Message)
+			if after.Step != nil {
+				text = fmt.Sprintf("After hook [%s] failed: %s\n%s", after.Step.Text, after.Message, after.Stack)
+				message = fmt.Sprintf("[after hook: %s] %s", after.Step.Text, after.Message)
+			}
+			if scenario.Exception == nil {
+				tc.Failure.Type = "AfterHook"
+				tc.Failure.Message = message
+				tc.Failure.Content = text
+			} else {
+				tc.Failure.Content += "\n" + text
+			}
+		}
+	default:
+		tc.Skipped = new(junitSkipped)
+		tc.Skipped.Message = fmt.Sprintf("Scenario is %s", statusName(scenario.Status))
+	}
+	return tc
+}
+
+// Write the result as JUnit XML
+// @params:
+//    w: Where the XML goes
+// @returns:
+//    (error): Errors of encoding or writing
+func (v *Result) WriteJUnit(w io.Writer) error {
+	doc := new(junitTestSuites)
+	doc.Time = junitTime(v.Duration)
+	doc.Suites = make([]*junitTestSuite, 0, len(v.Features))
+
+	for _, feature := range v.Features {
+		suite := new(junitTestSuite)
+		suite.Name = feature.Name
+		suite.Time = junitTime(feature.Duration)
+		if !feature.StartTime.IsZero() {
+			suite.Timestamp = feature.StartTime.Format("2006-01-02T15:04:05")
+		}
+		suite.TestCases = make([]*junitTestCase, 0, len(feature.Scenarios))
+		for _, scenario := range feature.Scenarios {
+			tc := newJUnitTestCase(feature, scenario)
+			suite.Tests++
+			if tc.Failure != nil {
+				suite.Failures++
+			}
+			if tc.Skipped != nil {
+				suite.Skipped++
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		// Failure of feature hooks goes into a test case of its own
+		if exception := feature.hookException(); exception != nil {
+			tc := new(junitTestCase)
+			tc.Name = "Feature hooks"
+			tc.ClassName = feature.Name
+			tc.File = feature.Path
+			tc.Time = junitTime(0)
+			tc.Failure = &junitFailure{Message: exception.Message, Type: "FeatureHook", Content: exception.Stack}
+			suite.Tests++
+			suite.Failures++
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
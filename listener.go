@@ -0,0 +1,116 @@
+package go2test
+
+import (
+	"bytes"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ----------------------------------------------------------------------------------
+// @name: Listener
+// Receives the events of a run, register it by Go2Test.AddListener()
+// Embed BaseListener to implement only the events you need
+// Events come from many goroutines if Go2Test.SetConcurrency() > 1
+// ----------------------------------------------------------------------------------
+type Listener interface {
+	RunStarted(result *Result)
+	FeatureStarted(handle *Handle, feature *Feature)
+	ScenarioStarted(handle *Handle, scenario *Scenario)
+	StepStarted(handle *Handle, step *Step)
+	StepPassed(handle *Handle, step *Step)
+	StepFailed(handle *Handle, step *Step, exception *Exception)
+	StepSkipped(handle *Handle, step *Step)
+	StepUndefined(handle *Handle, step *Step)
+	ScenarioFinished(handle *Handle, scenario *Scenario)
+	FeatureFinished(handle *Handle, feature *Feature)
+	RunFinished(result *Result)
+}
+
+
+// ----------------------------------------------------------------------------------
+// @name: BaseListener
+// Listener which does nothing
+// ----------------------------------------------------------------------------------
+type BaseListener struct {
+}
+
+func (v *BaseListener) RunStarted(result *Result) {}
+func (v *BaseListener) FeatureStarted(handle *Handle, feature *Feature) {}
+func (v *BaseListener) ScenarioStarted(handle *Handle, scenario *Scenario) {}
+func (v *BaseListener) StepStarted(handle *Handle, step *Step) {}
+func (v *BaseListener) StepPassed(handle *Handle, step *Step) {}
+func (v *BaseListener) StepFailed(handle *Handle, step *Step, exception *Exception) {}
+func (v *BaseListener) StepSkipped(handle *Handle, step *Step) {}
+func (v *BaseListener) StepUndefined(handle *Handle, step *Step) {}
+func (v *BaseListener) ScenarioFinished(handle *Handle, scenario *Scenario) {}
+func (v *BaseListener) FeatureFinished(handle *Handle, feature *Feature) {}
+func (v *BaseListener) RunFinished(result *Result) {}
+
+
+// ----------------------------------------------------------------------------------
+// @name: LogListener
+// The default listener, writes the progress to logrus
+// ----------------------------------------------------------------------------------
+type LogListener struct {
+	BaseListener
+}
+
+func (v *LogListener) ScenarioStarted(handle *Handle, scenario *Scenario) {
+	log.Infof(" ")
+	log.Infof("----------------------------------------")
+	log.Infof("%s.%s", handle.Feature.Name, scenario.Name)
+	log.Infof("----------------------------------------")
+}
+
+func (v *LogListener) StepStarted(handle *Handle, step *Step) {
+	log.Infof("[STEP] %s", step.Text)
+}
+
+func (v *LogListener) StepFailed(handle *Handle, step *Step, exception *Exception) {
+	log.Errorf(" ")
+	log.Errorf("|    FAIL!!!")
+	log.Errorf("|    %s", exception.Message)
+	msgs := strings.Split(exception.Stack, "\n")
+	for _, msg := range msgs {
+		log.Errorf("|    %s ", msg)
+	}
+	log.Errorf(" ")
+}
+
+func (v *LogListener) StepSkipped(handle *Handle, step *Step) {
+	log.Infof("[ SKIP ] %s", step.Text)
+}
+
+func (v *LogListener) StepUndefined(handle *Handle, step *Step) {
+	log.Warnf("[ %s ] %s", strings.ToUpper(statusName(step.Status)), step.Text)
+}
+
+func (v *LogListener) RunFinished(result *Result) {
+	undefined := result.UndefinedSteps()
+	if len(undefined) == 0 {
+		return
+	}
+	log.Warnf(" ")
+	log.Warnf("%d undefined or ambiguous steps:", len(undefined))
+	for _, step := range undefined {
+		log.Warnf("[ %s ] %s", strings.ToUpper(statusName(step.Status)), step.Text)
+		for _, location := range step.Locations {
+			log.Warnf("    %s", location)
+		}
+		for _, line := range strings.Split(step.Message, "\n")[1:] {
+			log.Warnf("%s", line)
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+	result.WriteSnippets(buffer)
+	if buffer.Len() == 0 {
+		return
+	}
+	log.Warnf(" ")
+	log.Warnf("You can implement undefined steps with these snippets:")
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		log.Warnf("%s", line)
+	}
+}
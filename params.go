@@ -0,0 +1,173 @@
+package go2test
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var handleType = reflect.TypeOf((*Handle)(nil))
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var stringSliceType = reflect.TypeOf([]string{})
+var mapSliceType = reflect.TypeOf([]map[string]string{})
+
+// Check action's signature: func(*Handle, [context.Context], [table], captures...)
+// @params:
+//    action: func added by AddAction()
+//    captures: Number of capture groups in regex
+//    params: Parameter type of each capture group, nil means converted by convertParam()
+// @returns:
+//    (error): Why the action cannot be called
+func checkAction(action reflect.Value, captures int, params []*parameterType) error {
+	if action.Kind() != reflect.Func {
+		return fmt.Errorf("expect func, got %s", action.Kind())
+	}
+	actionType := action.Type()
+	if actionType.IsVariadic() {
+		return fmt.Errorf("variadic func is not supported")
+	}
+	if actionType.NumIn() < 1 || actionType.In(0) != handleType {
+		return fmt.Errorf("the first param must be *Handle")
+	}
+
+	first := paramOffset(actionType)
+	switch actionType.NumIn() - first {
+	case captures:
+	case captures + 1:
+		// The special param goes before captures
+		if !isArgumentType(actionType.In(first)) {
+			return fmt.Errorf("param %d must be a table (DataTable, []string, []map[string]string, []struct) or a DocString, got %s",
+				first, actionType.In(first))
+		}
+		first++
+	default:
+		return fmt.Errorf("regex has %d capture groups, but func accepts %d params after *Handle",
+			captures, actionType.NumIn()-1)
+	}
+
+	for i := first; i < actionType.NumIn(); i++ {
+		if params != nil && params[i-first] != nil {
+			if !params[i-first].accepts(actionType.In(i)) {
+				return fmt.Errorf("param %d: cannot convert {%s} to %s", i, params[i-first].name, actionType.In(i))
+			}
+			continue
+		}
+		if !isConvertibleType(actionType.In(i)) {
+			return fmt.Errorf("param %d: cannot convert text to %s", i, actionType.In(i))
+		}
+	}
+	return nil
+}
+
+// Index of the first param filled by step
+// Params before it are given by Go2Test: *Handle and optional context.Context
+func paramOffset(actionType reflect.Type) int {
+	if actionType.NumIn() > 1 && actionType.In(1) == contextType {
+		return 2
+	}
+	return 1
+}
+
+// Whether the type can receive the step's table or DocString
+func isArgumentType(typ reflect.Type) bool {
+	return isTableType(typ) || isDocStringType(typ)
+}
+
+// Whether the type can be converted by convertParam()
+func isConvertibleType(typ reflect.Type) bool {
+	if typ == durationType || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Interface:
+		return reflect.TypeOf("").AssignableTo(typ)
+	}
+	return false
+}
+
+// Whether convertParam() converts the type without a parameter type
+// Predeclared types, e.g. string, int, float64, interface types and time.Duration
+func isBuiltinType(typ reflect.Type) bool {
+	if typ == durationType || typ.Kind() == reflect.Interface {
+		return true
+	}
+	return typ.PkgPath() == "" && typ.Name() != "" && isConvertibleType(typ)
+}
+
+// Convert text matched by regex into the type declared by action
+// Supports string, bool, int*, uint*, float*, time.Duration, interface{} and encoding.TextUnmarshaler
+// @params:
+//    text: Matched text
+//    typ: Type of action's param
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Error if text cannot be converted
+func convertParam(text string, typ reflect.Type) (reflect.Value, error) {
+	value := reflect.New(typ).Elem()
+
+	if typ == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(int64(d))
+		return value, nil
+	}
+
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		ptr := reflect.New(typ)
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return value, err
+		}
+		return ptr.Elem(), nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(text), 10, typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetFloat(f)
+	case reflect.Interface:
+		if !reflect.TypeOf(text).AssignableTo(typ) {
+			return value, fmt.Errorf("string does not implement %s", typ)
+		}
+		value.Set(reflect.ValueOf(text))
+	default:
+		return value, fmt.Errorf("unsupported type %s", typ)
+	}
+	return value, nil
+}
@@ -0,0 +1,212 @@
+package go2test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------------
+// @name: Counter
+// Aggregate status counts of features, scenarios or steps
+// @values
+//    - Total: Number of items
+//    - Passed: Number of G2T_STATUS_PASS
+//    - Failed: Number of G2T_STATUS_FAIL
+//    - Skipped: Number of G2T_STATUS_SKIP
+//    - Undefined: Number of G2T_STATUS_UNDEFINED
+//    - Ambiguous: Number of G2T_STATUS_AMBIGUOUS
+//    - Waiting: Number of G2T_STATUS_WAIT (never ran)
+// ----------------------------------------------------------------------------------
+type Counter struct {
+	Total      int
+	Passed     int
+	Failed     int
+	Skipped    int
+	Undefined  int
+	Ambiguous  int
+	Waiting    int
+}
+
+// Count one more item with the given status
+func (v *Counter) add(status int) {
+	v.Total++
+	switch status {
+	case G2T_STATUS_PASS:
+		v.Passed++
+	case G2T_STATUS_FAIL:
+		v.Failed++
+	case G2T_STATUS_SKIP:
+		v.Skipped++
+	case G2T_STATUS_UNDEFINED:
+		v.Undefined++
+	case G2T_STATUS_AMBIGUOUS:
+		v.Ambiguous++
+	default:
+		v.Waiting++
+	}
+}
+
+// Readable name of G2T_STATUS_*
+// @params:
+//    status: G2T_STATUS_*
+// @returns:
+//    (string): passed|failed|skipped|undefined|ambiguous|waiting
+func statusName(status int) string {
+	switch status {
+	case G2T_STATUS_PASS:
+		return "passed"
+	case G2T_STATUS_FAIL:
+		return "failed"
+	case G2T_STATUS_SKIP:
+		return "skipped"
+	case G2T_STATUS_UNDEFINED:
+		return "undefined"
+	case G2T_STATUS_AMBIGUOUS:
+		return "ambiguous"
+	default:
+		return "waiting"
+	}
+}
+
+// ----------------------------------------------------------------------------------
+// @name: UndefinedStep
+// Undefined or ambiguous steps with the same text
+// @values
+//    - Text: Text of step
+//    - Status: G2T_STATUS_UNDEFINED|G2T_STATUS_AMBIGUOUS
+//    - Message: Why it's undefined
+//    - Locations: "path:line" of every step with the text
+//    - Steps: All steps with the text
+// ----------------------------------------------------------------------------------
+type UndefinedStep struct {
+	Text       string
+	Status     int
+	Message    string
+	Locations  []string
+	Steps      []*Step
+}
+
+// ----------------------------------------------------------------------------------
+// @name: Result
+// The result tree of one Go2Test run
+// @values
+//    - Features: All features which ran, with their scenarios and steps
+//    - StartTime: When the run started
+//    - Duration: How long the run took
+//    - FeatureCount: Aggregate counts of features
+//    - ScenarioCount: Aggregate counts of scenarios
+//    - StepCount: Aggregate counts of steps
+//    - Errors: Undefined and ambiguous steps, and all errors collected in dry-run mode
+// ----------------------------------------------------------------------------------
+type Result struct {
+	Features         []*Feature
+	StartTime        time.Time
+	Duration         time.Duration
+	FeatureCount     Counter
+	ScenarioCount    Counter
+	StepCount        Counter
+	Errors           []*Exception
+	actions          []*stepAction
+	hookSources      []*gherkinSource
+}
+
+// Create new *Result and start its clock
+// @params:
+//    features: Features going to run
+// @returns:
+//    (*Result): new *Result
+func newResult(features []*Feature) *Result {
+	v := new(Result)
+	v.Features = features
+	v.StartTime = time.Now()
+	return v
+}
+
+// Stop the clock and count the status of all features, scenarios and steps
+func (v *Result) finish() {
+	v.Duration = time.Since(v.StartTime)
+	v.FeatureCount = Counter{}
+	v.ScenarioCount = Counter{}
+	v.StepCount = Counter{}
+	for _, feature := range v.Features {
+		v.FeatureCount.add(feature.Status)
+		for _, scenario := range feature.Scenarios {
+			v.ScenarioCount.add(scenario.Status)
+			for _, step := range scenario.AllSteps() {
+				v.StepCount.add(step.Status)
+			}
+		}
+	}
+}
+
+// Whether any feature failed
+// @returns:
+//    (bool): true if at least one scenario failed, or any error is collected
+func (v *Result) Failed() bool {
+	return v.FeatureCount.Failed > 0 || len(v.Errors) > 0
+}
+
+// Undefined and ambiguous steps grouped by text, in the order they are found
+// @returns:
+//    ([]*UndefinedStep): Groups of steps
+func (v *Result) UndefinedSteps() []*UndefinedStep {
+	ret := make([]*UndefinedStep, 0)
+	groups := make(map[string]*UndefinedStep)
+	seen := make(map[string]bool)
+	for _, feature := range v.Features {
+		for _, scenario := range feature.Scenarios {
+			for _, step := range scenario.AllSteps() {
+				if !step.undefined() {
+					continue
+				}
+				group, ok := groups[step.Text]
+				if !ok {
+					group = &UndefinedStep{Text: step.Text, Status: step.Status}
+					if step.Exception != nil {
+						group.Message = step.Exception.Message
+					}
+					groups[step.Text] = group
+					ret = append(ret, group)
+				}
+				group.Steps = append(group.Steps, step)
+				// Background steps are shared by scenarios, list its location once
+				location := fmt.Sprintf("%s:%d", step.Path, step.Line)
+				if !seen[step.Text+"\n"+location] {
+					seen[step.Text+"\n"+location] = true
+					group.Locations = append(group.Locations, location)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// All collected errors in one message
+// Undefined steps are grouped by text, other errors are listed one per line
+func (v *Result) errorsMessage() string {
+	lines := make([]string, 0, len(v.Errors)+1)
+	lines = append(lines, fmt.Sprintf("Found %d errors:", len(v.Errors)))
+	for _, group := range v.UndefinedSteps() {
+		lines = append(lines, fmt.Sprintf("    %s step [%s]:", strings.Title(statusName(group.Status)), group.Text))
+		for _, location := range group.Locations {
+			lines = append(lines, "        "+location)
+		}
+		// Matched actions of ambiguous step
+		for _, line := range strings.Split(group.Message, "\n")[1:] {
+			lines = append(lines, "    "+line)
+		}
+	}
+	for _, err := range v.Errors {
+		if err.Step != nil && err.Step.undefined() {
+			continue
+		}
+		if err.Location() == "" {
+			// e.g. failure of BeforeSuite hooks
+			lines = append(lines, "    "+err.Message)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("    %s: %s", err.Location(), err.Message))
+	}
+	return strings.Join(lines, "\n")
+}
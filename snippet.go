@@ -0,0 +1,130 @@
+package go2test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Parts of step text which become capture groups of snippet
+// Numbers must be whole words, so "P2" or "1m30s" stay as they are
+var snippetArgs = regexp.MustCompile(`"[^"]*"|'[^']*'|-?\b\d+(?:\.\d+)?\b`)
+
+// Create the regex and params of snippet from step text
+// @params:
+//    text: Text of step
+// @returns:
+//    (string): Regex which matches the text, e.g. ^I have (-?\d+) "([^"]*)"$
+//    ([]string): Go types of capture groups, e.g. ["int", "string"]
+func snippetRegex(text string) (string, []string) {
+	types := make([]string, 0)
+	regex := "^"
+	last := 0
+	for _, loc := range snippetArgs.FindAllStringIndex(text, -1) {
+		regex += regexp.QuoteMeta(text[last:loc[0]])
+		arg := text[loc[0]:loc[1]]
+		switch {
+		case arg[0] == '"':
+			regex += `"([^"]*)"`
+			types = append(types, "string")
+		case arg[0] == '\'':
+			regex += `'([^']*)'`
+			types = append(types, "string")
+		case strings.Contains(arg, "."):
+			regex += `(-?\d+\.\d+)`
+			types = append(types, "float64")
+		default:
+			regex += `(-?\d+)`
+			types = append(types, "int")
+		}
+		last = loc[1]
+	}
+	regex += regexp.QuoteMeta(text[last:]) + "$"
+	return regex, types
+}
+
+// ----------------------------------------------------------------------------------
+// Create a ready-to-paste AddAction() for the undefined step
+// Quoted strings and numbers become capture groups, data table or DocString becomes the first param
+// @returns:
+//    (string): Go code, empty if the step is ambiguous
+// ----------------------------------------------------------------------------------
+func (v *UndefinedStep) Snippet() string {
+	if v.Status != G2T_STATUS_UNDEFINED {
+		return ""
+	}
+	regex, types := snippetRegex(v.Text)
+	params := []string{"handle *Handle"}
+	if len(v.Steps) > 0 && v.Steps[0].Rows != nil {
+		params = append(params, "table "+stringSliceType.String())
+		if len(v.Steps[0].Rows[0]) > 1 {
+			params[len(params)-1] = "table "+mapSliceType.String()
+		}
+	}
+	if len(v.Steps) > 0 && v.Steps[0].DocString != nil {
+		params = append(params, "doc string")
+	}
+	for idx, typ := range types {
+		params = append(params, fmt.Sprintf("arg%d %s", idx+1, typ))
+	}
+
+	quoted := "`" + regex + "`"
+	if strings.Contains(regex, "`") {
+		quoted = strconv.Quote(regex)
+	}
+	return fmt.Sprintf("go2test.AddAction(%s, func(%s) {\n\thandle.ThrowException(\"Not implemented\")\n})\n",
+		quoted, strings.Join(params, ", "))
+}
+
+// Write snippets of all undefined steps, steps with the same snippet are written once
+// @params:
+//    w: Where the snippets go
+// @returns:
+//    (error): Errors of writing
+func (v *Result) WriteSnippets(w io.Writer) error {
+	written := make(map[string]bool)
+	for _, step := range v.UndefinedSteps() {
+		snippet := step.Snippet()
+		if snippet == "" || written[snippet] {
+			continue
+		}
+		// Snippets are separated by a blank line
+		separator := "\n"
+		if len(written) == 0 {
+			separator = ""
+		}
+		written[snippet] = true
+		if _, err := io.WriteString(w, separator+snippet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write snippets into the file set by Go2Test.SetSnippetFile()
+// The file of previous run is removed if no undefined step
+func (v *Go2Test) writeSnippetFile(result *Result) {
+	if v.snippetFile == "" {
+		return
+	}
+	buffer := new(bytes.Buffer)
+	result.WriteSnippets(buffer)
+	if buffer.Len() == 0 {
+		if err := os.Remove(v.snippetFile); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Cannot remove snippets [%s]: %s", v.snippetFile, err.Error())
+		}
+		return
+	}
+	if err := ioutil.WriteFile(v.snippetFile, buffer.Bytes(), 0644); err != nil {
+		log.Errorf("Cannot write snippets into [%s]: %s", v.snippetFile, err.Error())
+		return
+	}
+	log.Infof("Snippets of undefined steps are written into [%s]", v.snippetFile)
+}
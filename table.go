@@ -0,0 +1,156 @@
+package go2test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Whether the type can receive the step's data table
+// DataTable, *DataTable, []string, []map[string]string, []struct, []*struct, or struct, *struct by a key/value table
+func isTableType(typ reflect.Type) bool {
+	if typ == stringSliceType || typ == mapSliceType || typ == dataTableType || typ == dataTablePtrType {
+		return true
+	}
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}
+
+// Convert cells of data table into the type declared by action
+// @params:
+//    rows: Cells of table
+//    typ: Type of action's param
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Table does not fit the type, or conversion error of cells
+func (v *Go2Test) convertTable(rows [][]string, typ reflect.Type) (reflect.Value, error) {
+	switch {
+	case typ == dataTablePtrType:
+		return reflect.ValueOf(NewDataTable(rows)), nil
+	case typ == dataTableType:
+		return reflect.ValueOf(*NewDataTable(rows)), nil
+	case typ == stringSliceType:
+		if len(rows[0]) > 1 {
+			return reflect.Value{}, fmt.Errorf("table has %d columns, []string needs 1", len(rows[0]))
+		}
+		param := make([]string, 0, len(rows))
+		for _, row := range rows {
+			param = append(param, row[0])
+		}
+		return reflect.ValueOf(param), nil
+	case typ == mapSliceType:
+		if len(rows[0]) < 2 {
+			return reflect.Value{}, fmt.Errorf("table has 1 column, use []string instead")
+		}
+		param := make([]map[string]string, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			r := make(map[string]string)
+			for idx, cell := range row {
+				r[rows[0][idx]] = cell
+			}
+			param = append(param, r)
+		}
+		return reflect.ValueOf(param), nil
+	case typ.Kind() == reflect.Slice:
+		// The first row is header, every other row is an item
+		param := reflect.MakeSlice(typ, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			item, err := v.convertTableRow(rows[0], row, typ.Elem())
+			if err != nil {
+				return param, err
+			}
+			param = reflect.Append(param, item)
+		}
+		return param, nil
+	}
+
+	// Key/value table, every row is a field
+	keys := make([]string, 0, len(rows))
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 2 {
+			return reflect.Value{}, fmt.Errorf("table bound to %s must have 2 columns: key | value", typ)
+		}
+		keys = append(keys, row[0])
+		values = append(values, row[1])
+	}
+	return v.convertTableRow(keys, values, typ)
+}
+
+// Convert a row into struct, headers are mapped to fields
+// @params:
+//    headers: Header of each cell
+//    row: Cells
+//    typ: struct or *struct
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Unknown header or conversion error of cells
+func (v *Go2Test) convertTableRow(headers []string, row []string, typ reflect.Type) (reflect.Value, error) {
+	structType := typ
+	if typ.Kind() == reflect.Ptr {
+		structType = typ.Elem()
+	}
+	ptr := reflect.New(structType)
+	for idx, header := range headers {
+		field, ok := tableField(structType, header)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("no field of %s for column [%s]", structType, header)
+		}
+		value, err := v.convertText(row[idx], field.Type)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot convert [%s] of column [%s] to %s: %s",
+				row[idx], header, field.Type, err.Error())
+		}
+		ptr.Elem().FieldByIndex(field.Index).Set(value)
+	}
+	if typ.Kind() == reflect.Ptr {
+		return ptr, nil
+	}
+	return ptr.Elem(), nil
+}
+
+// Find the field of column
+// Tag g2t:"Header" wins, otherwise the field name, case and spaces are ignored, e.g. "First name" => FirstName
+// Fields tagged g2t:"-" and unexported fields are ignored
+func tableField(structType reflect.Type, header string) (reflect.StructField, bool) {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+	}
+	var byName *reflect.StructField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("g2t")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		if tag != "" && tag == strings.TrimSpace(header) {
+			return field, true
+		}
+		if tag == "" && byName == nil && normalize(field.Name) == normalize(header) {
+			byName = &field
+		}
+	}
+	if byName != nil {
+		return *byName, true
+	}
+	return reflect.StructField{}, false
+}
+
+// Convert text into the type, by the parameter type added for it or convertParam()
+// @params:
+//    text: Text of cell
+//    typ: Type of value
+// @returns:
+//    (reflect.Value): Converted value
+//    (error): Conversion error
+func (v *Go2Test) convertText(text string, typ reflect.Type) (reflect.Value, error) {
+	if param := v.parameterTypeOf(typ); param != nil {
+		return param.convert(text, typ)
+	}
+	return convertParam(text, typ)
+}
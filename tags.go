@@ -0,0 +1,280 @@
+package go2test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scenarios with this tag never run in parallel
+var serialTag = &tagLiteral{name: "@serial"}
+
+// Tag sets the timeout of scenario, e.g. @timeout(30s)
+var timeoutTag = regexp.MustCompile(`^@timeout\((.+)\)$`)
+
+// ----------------------------------------------------------------------------------
+// @name: TagExpression
+// Boolean expression of tags, e.g. "(@api or @ui) and not @wip"
+// Operators by priority: not > and > or, parentheses group sub expressions
+// Please use ParseTagExpression() to create new TagExpression
+// ----------------------------------------------------------------------------------
+type TagExpression interface {
+	// Whether the tags match the expression
+	Evaluate(tags []string) bool
+	String() string
+}
+
+type tagLiteral struct {
+	name string
+}
+
+func (v *tagLiteral) Evaluate(tags []string) bool {
+	for _, tag := range tags {
+		if tag == v.name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *tagLiteral) String() string {
+	return v.name
+}
+
+type tagNot struct {
+	expr TagExpression
+}
+
+func (v *tagNot) Evaluate(tags []string) bool {
+	return !v.expr.Evaluate(tags)
+}
+
+func (v *tagNot) String() string {
+	return "not ( " + v.expr.String() + " )"
+}
+
+type tagAnd struct {
+	left, right TagExpression
+}
+
+func (v *tagAnd) Evaluate(tags []string) bool {
+	return v.left.Evaluate(tags) && v.right.Evaluate(tags)
+}
+
+func (v *tagAnd) String() string {
+	return "( " + v.left.String() + " and " + v.right.String() + " )"
+}
+
+type tagOr struct {
+	left, right TagExpression
+}
+
+func (v *tagOr) Evaluate(tags []string) bool {
+	return v.left.Evaluate(tags) || v.right.Evaluate(tags)
+}
+
+func (v *tagOr) String() string {
+	return "( " + v.left.String() + " or " + v.right.String() + " )"
+}
+
+
+// Parse tag expression
+// @params:
+//    text: e.g. "@smoke and not @slow"
+// @returns:
+//    (TagExpression): Parsed expression
+//    (error): Syntax error
+func ParseTagExpression(text string) (TagExpression, error) {
+	parser := &tagParser{tokens: tokenizeTags(text)}
+	if len(parser.tokens) == 0 {
+		return nil, fmt.Errorf("empty tag expression")
+	}
+	expr, err := parser.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag expression [%s]: %s", text, err.Error())
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("invalid tag expression [%s]: unexpected [%s]", text, parser.tokens[parser.pos])
+	}
+	return expr, nil
+}
+
+
+// Compile tag filters of Run(), every item is a tag expression and matched if any of them matched
+// @params:
+//    tags: e.g. ["@Tag1", "@smoke and not @slow"]
+// @returns:
+//    (TagExpression): nil if no filter
+//    (error): Syntax error
+func compileTagFilter(tags []string) (TagExpression, error) {
+	var filter TagExpression
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		expr, err := ParseTagExpression(tag)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			filter = expr
+		} else {
+			filter = &tagOr{left: filter, right: expr}
+		}
+	}
+	return filter, nil
+}
+
+
+// Find @timeout(duration) in tags, the last one wins
+// @params:
+//    tags: Tags of scenario
+// @returns:
+//    (time.Duration): 0 if not found
+//    (error): Invalid duration
+func parseTimeoutTag(tags []string) (time.Duration, error) {
+	timeout := time.Duration(0)
+	for _, tag := range tags {
+		matched := timeoutTag.FindStringSubmatch(tag)
+		if len(matched) == 0 {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(matched[1]))
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid tag [%s]", tag)
+		}
+		timeout = d
+	}
+	return timeout, nil
+}
+
+
+// Whether tags pass the filter, everything passes nil filter
+func matchTags(filter TagExpression, tags []string) bool {
+	return filter == nil || filter.Evaluate(tags)
+}
+
+
+// Split expression into tags, keywords and parentheses
+// Parentheses inside a tag are part of it, e.g. @timeout(30s)
+func tokenizeTags(text string) []string {
+	tokens := make([]string, 0)
+	token := ""
+	depth := 0  // open parentheses inside the tag
+	flush := func() {
+		if token != "" {
+			tokens = append(tokens, token)
+			token = ""
+		}
+	}
+	for _, c := range text {
+		switch {
+		case depth > 0:
+			token += string(c)
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+		case c == '(' && strings.HasPrefix(token, "@"):
+			token += string(c)
+			depth++
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		default:
+			token += string(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Recursive descent parser of tag expression
+type tagParser struct {
+	tokens []string
+	pos    int
+}
+
+func (v *tagParser) peek() string {
+	if v.pos < len(v.tokens) {
+		return v.tokens[v.pos]
+	}
+	return ""
+}
+
+func (v *tagParser) parseOr() (TagExpression, error) {
+	left, err := v.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for v.peek() == "or" {
+		v.pos++
+		right, err := v.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &tagOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (v *tagParser) parseAnd() (TagExpression, error) {
+	left, err := v.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for v.peek() == "and" {
+		v.pos++
+		right, err := v.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &tagAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (v *tagParser) parseNot() (TagExpression, error) {
+	if v.peek() == "not" {
+		v.pos++
+		expr, err := v.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &tagNot{expr: expr}, nil
+	}
+	return v.parseOperand()
+}
+
+func (v *tagParser) parseOperand() (TagExpression, error) {
+	token := v.peek()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end")
+	case "(":
+		v.pos++
+		expr, err := v.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if v.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		v.pos++
+		return expr, nil
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected [%s]", token)
+	}
+	if !strings.HasPrefix(token, "@") {
+		return nil, fmt.Errorf("tag [%s] must start with @", token)
+	}
+	if strings.Count(token, "(") != strings.Count(token, ")") {
+		return nil, fmt.Errorf("missing ) in tag [%s]", token)
+	}
+	v.pos++
+	return &tagLiteral{name: token}, nil
+}
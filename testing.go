@@ -0,0 +1,107 @@
+package go2test
+
+import (
+	"testing"
+)
+
+// Start to run Go2Test framework inside "go test"
+// Every feature and scenario runs as subtest, so -run 'Feature/Scenario', -v and caching work per scenario
+// @params:
+//     t: *testing.T of the test function
+//     path: test files location ( where *.feature is )
+//     tags: filter by tag expressions, see Run()
+// @returns:
+//     (*Result): Features, scenarios and steps with their status, nil if parse/setup failed
+func (v *Go2Test) RunT(t *testing.T, path string, tags []string) *Result {
+	t.Helper()
+
+	features, exp := v.loadFeatures(path, tags)
+	if exp != nil {
+		t.Fatalf("%s\n%s", exp.Message, exp.Stack)
+		return nil
+	}
+
+	result := v.startResult(features)
+	for _, err := range result.Errors {
+		t.Errorf("%s: %s", err.Location(), err.Message)
+	}
+	v.handle.notify(func(l Listener) { l.RunStarted(result) })
+	// No feature runs if BeforeSuite hooks failed
+	// BeforeSuite and AfterSuite hooks share a handle, so the Buffer keeps what they set up
+	suiteHandle := v.newHandle(nil)
+	if exception := v.runHooks(hookBeforeSuite, suiteHandle, nil); exception != nil {
+		t.Errorf("%s", exception.Message)
+		features = nil
+	}
+	for _, feature := range features {
+		t.Run(feature.Name, func(t *testing.T) {
+			feature.run(v.handle, v.concurrency, func(scenario *Scenario) {
+				t.Run(scenario.Name, func(t *testing.T) {
+					v.runScenario(feature, scenario)
+					switch scenario.Status {
+					case G2T_STATUS_FAIL, G2T_STATUS_UNDEFINED, G2T_STATUS_AMBIGUOUS:
+						reportT(t, scenario)
+					case G2T_STATUS_SKIP:
+						t.SkipNow()
+					}
+				})
+				skipFiltered(scenario)
+			})
+			if exception := feature.hookException(); exception != nil {
+				t.Errorf("%s", exception.Message)
+			}
+		})
+		// The whole feature is filtered out by -run
+		if feature.Status == G2T_STATUS_WAIT {
+			for _, scenario := range feature.Scenarios {
+				skipFiltered(scenario)
+			}
+			feature.Status = G2T_STATUS_SKIP
+		}
+	}
+	if exception := v.runHooks(hookAfterSuite, suiteHandle, nil); exception != nil {
+		t.Errorf("%s", exception.Message)
+	}
+	result.finish()
+	v.handle.notify(func(l Listener) { l.RunFinished(result) })
+	v.writeSnippetFile(result)
+
+	return result
+}
+
+// Skip the scenario filtered out by -run, its subtest did not run it
+// Undefined or ambiguous steps keep their status
+func skipFiltered(scenario *Scenario) {
+	if scenario.Status != G2T_STATUS_WAIT {
+		return
+	}
+	scenario.Status = G2T_STATUS_SKIP
+	for _, step := range scenario.AllSteps() {
+		if !step.undefined() {
+			step.Status = G2T_STATUS_SKIP
+		}
+	}
+}
+
+// Mark the subtest failed with the failed step, and the failed after hook
+func reportT(t *testing.T, scenario *Scenario) {
+	if after := scenario.AfterException; after != nil {
+		if after.Step != nil {
+			t.Errorf("After hook [%s] failed: %s", after.Step.Text, after.Message)
+		} else {
+			t.Errorf("%s", after.Message)
+		}
+	}
+	exception := scenario.Exception
+	if exception == nil {
+		if scenario.AfterException == nil {
+			t.Errorf("Scenario [%s] failed", scenario.Name)
+		}
+		return
+	}
+	if exception.Step != nil {
+		t.Errorf("Step [%s] failed: %s", exception.Step.Text, exception.Message)
+		return
+	}
+	t.Errorf("%s", exception.Message)
+}